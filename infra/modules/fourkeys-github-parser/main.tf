@@ -2,60 +2,6 @@ data "google_project" "project" {
   project_id = var.project_id
 }
 
-locals {
-  services = var.enable_apis ? [
-    "run.googleapis.com"
-  ] : []
-}
-
-resource "google_project_service" "data_source_services" {
-  project                    = var.project_id
-  for_each                   = toset(local.services)
-  service                    = each.value
-  disable_on_destroy         = false
-}
-
-resource "google_cloud_run_service" "github_parser" {
-  project  = var.project_id
-  name     = "fourkeys-github-parser"
-  location = var.region
-
-  template {
-    spec {
-      containers {
-        image = var.parser_container_url
-        env {
-          name  = "PROJECT_NAME"
-          value = var.project_id
-        }
-      }
-      service_account_name = var.fourkeys_service_account_email
-    }
-  }
-
-  traffic {
-    percent         = 100
-    latest_revision = true
-  }
-
-  metadata {
-    annotations = {
-      "run.googleapis.com/ingress" = "internal"
-    }
-  }
-
-  lifecycle {
-    ignore_changes = [
-      metadata[0].annotations,
-    ]
-  }
-
-  autogenerate_revision_name = true
-  depends_on = [
-    google_project_service.data_source_services
-  ]
-}
-
 resource "google_pubsub_topic" "github" {
   project = var.project_id
   name    = "github"
@@ -74,7 +20,7 @@ resource "google_pubsub_subscription" "github" {
   topic   = google_pubsub_topic.github.id
 
   push_config {
-    push_endpoint = google_cloud_run_service.github_parser.status[0]["url"]
+    push_endpoint = var.push_endpoint
 
     oidc_token {
       service_account_email = var.fourkeys_service_account_email
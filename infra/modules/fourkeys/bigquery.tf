@@ -25,6 +25,32 @@ resource "google_bigquery_table" "events_raw" {
   ]
 }
 
+resource "google_bigquery_table" "events_dead_letter" {
+  project             = var.project_id
+  dataset_id          = google_bigquery_dataset.four_keys.dataset_id
+  table_id            = "events_dead_letter"
+  schema              = file("${path.module}/files/events_dead_letter_schema.json")
+  deletion_protection = false
+  depends_on = [
+    google_project_service.fourkeys_services
+  ]
+}
+
+resource "google_bigquery_table" "view_events" {
+  project    = var.project_id
+  dataset_id = google_bigquery_dataset.four_keys.dataset_id
+  table_id   = "events"
+  view {
+    query          = file("${path.module}/queries/events.sql")
+    use_legacy_sql = false
+  }
+  deletion_protection = false
+  depends_on = [
+    google_project_service.fourkeys_services,
+    google_bigquery_table.events_raw
+  ]
+}
+
 resource "google_bigquery_table" "view_changes" {
   project    = var.project_id
   dataset_id = google_bigquery_dataset.four_keys.dataset_id
@@ -36,7 +62,7 @@ resource "google_bigquery_table" "view_changes" {
   deletion_protection = false
   depends_on = [
     google_project_service.fourkeys_services,
-    google_bigquery_table.events_raw
+    google_bigquery_table.view_events
   ]
 }
 
@@ -82,7 +108,7 @@ resource "google_bigquery_table" "view_deployments" {
   deletion_protection = false
   depends_on = [
     google_project_service.fourkeys_services,
-    google_bigquery_table.events_raw,
+    google_bigquery_table.view_events,
     google_bigquery_routine.func_json2array
   ]
 }
@@ -98,7 +124,7 @@ resource "google_bigquery_table" "view_incidents" {
   deletion_protection = false
   depends_on = [
     google_project_service.fourkeys_services,
-    google_bigquery_table.events_raw,
+    google_bigquery_table.view_events,
     google_bigquery_table.view_deployments,
     google_bigquery_routine.func_multiFormatParseTimestamp
   ]
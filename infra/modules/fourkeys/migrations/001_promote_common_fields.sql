@@ -0,0 +1,52 @@
+# Promote common fields of events_raw to columns
+#
+# Terraform adds the new NULLABLE columns to four_keys.events_raw in place
+# (see files/events_raw_schema.json). Rows inserted before that only carry
+# the values inside metadata, so run this once to backfill them:
+#
+#   bq query --use_legacy_sql=false < migrations/001_promote_common_fields.sql
+#
+# The ALTER statements make the script safe to run before terraform apply as well.
+
+ALTER TABLE four_keys.events_raw
+ADD COLUMN IF NOT EXISTS is_default_branch BOOL,
+ADD COLUMN IF NOT EXISTS repository STRING,
+ADD COLUMN IF NOT EXISTS organization STRING,
+ADD COLUMN IF NOT EXISTS actor STRING,
+ADD COLUMN IF NOT EXISTS ref STRING,
+ADD COLUMN IF NOT EXISTS commit_sha STRING,
+ADD COLUMN IF NOT EXISTS environment STRING;
+
+UPDATE four_keys.events_raw
+SET
+repository = JSON_EXTRACT_SCALAR(metadata, '$.repository.full_name'),
+organization = COALESCE(
+  JSON_EXTRACT_SCALAR(metadata, '$.organization.login'),
+  JSON_EXTRACT_SCALAR(metadata, '$.repository.owner.login')),
+actor = JSON_EXTRACT_SCALAR(metadata, '$.sender.login'),
+ref = CASE event_type
+  WHEN "push" THEN JSON_EXTRACT_SCALAR(metadata, '$.ref')
+  WHEN "pull_request" THEN JSON_EXTRACT_SCALAR(metadata, '$.pull_request.base.ref')
+  WHEN "pull_request_review" THEN JSON_EXTRACT_SCALAR(metadata, '$.pull_request.base.ref')
+  WHEN "pull_request_review_comment" THEN JSON_EXTRACT_SCALAR(metadata, '$.pull_request.base.ref')
+  WHEN "check_run" THEN JSON_EXTRACT_SCALAR(metadata, '$.check_run.check_suite.head_branch')
+  WHEN "check_suite" THEN JSON_EXTRACT_SCALAR(metadata, '$.check_suite.head_branch')
+  WHEN "deployment_status" THEN JSON_EXTRACT_SCALAR(metadata, '$.deployment.ref')
+  WHEN "release" THEN JSON_EXTRACT_SCALAR(metadata, '$.release.target_commitish')
+  END,
+commit_sha = CASE event_type
+  WHEN "push" THEN COALESCE(JSON_EXTRACT_SCALAR(metadata, '$.after'), JSON_EXTRACT_SCALAR(metadata, '$.head_commit.id'))
+  WHEN "pull_request" THEN COALESCE(JSON_EXTRACT_SCALAR(metadata, '$.pull_request.merge_commit_sha'), JSON_EXTRACT_SCALAR(metadata, '$.pull_request.head.sha'))
+  WHEN "pull_request_review" THEN JSON_EXTRACT_SCALAR(metadata, '$.pull_request.head.sha')
+  WHEN "pull_request_review_comment" THEN COALESCE(JSON_EXTRACT_SCALAR(metadata, '$.comment.commit_id'), JSON_EXTRACT_SCALAR(metadata, '$.pull_request.head.sha'))
+  WHEN "check_run" THEN JSON_EXTRACT_SCALAR(metadata, '$.check_run.head_sha')
+  WHEN "check_suite" THEN JSON_EXTRACT_SCALAR(metadata, '$.check_suite.head_sha')
+  WHEN "deployment_status" THEN JSON_EXTRACT_SCALAR(metadata, '$.deployment.sha')
+  WHEN "status" THEN JSON_EXTRACT_SCALAR(metadata, '$.sha')
+  END,
+environment = CASE event_type
+  WHEN "deployment_status" THEN COALESCE(
+    JSON_EXTRACT_SCALAR(metadata, '$.deployment_status.environment'),
+    JSON_EXTRACT_SCALAR(metadata, '$.deployment.environment'))
+  END
+WHERE source LIKE "github%" AND repository IS NULL;
@@ -0,0 +1,10 @@
+# Mark reprocessed dead letters as resolved
+#
+# Terraform adds the NULLABLE resolution column to four_keys.events_dead_letter
+# in place (see files/events_dead_letter_schema.json). Run this before deploying
+# a parser whose reprocess command writes it, if terraform has not been applied yet:
+#
+#   bq query --use_legacy_sql=false < migrations/002_dead_letter_resolution.sql
+
+ALTER TABLE four_keys.events_dead_letter
+ADD COLUMN IF NOT EXISTS resolution STRING;
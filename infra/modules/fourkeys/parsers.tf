@@ -1,77 +1,97 @@
+# One service parses every source, each source module only adds its topic and push subscription.
+resource "google_cloud_run_service" "parser" {
+  project  = var.project_id
+  name     = "fourkeys-parser"
+  location = var.region
+
+  template {
+    spec {
+      containers {
+        image = local.parser_container_url
+        env {
+          name  = "PROJECT_NAME"
+          value = var.project_id
+        }
+        env {
+          name  = "PARSER_SOURCES"
+          value = join(",", [for p in var.parsers : replace(p, "-", "_")])
+        }
+        env {
+          name  = "OIDC_SERVICE_ACCOUNT_EMAIL"
+          value = google_service_account.fourkeys.email
+        }
+      }
+      service_account_name = google_service_account.fourkeys.email
+    }
+  }
+
+  traffic {
+    percent         = 100
+    latest_revision = true
+  }
+
+  metadata {
+    annotations = {
+      "run.googleapis.com/ingress" = "internal"
+    }
+  }
+
+  lifecycle {
+    ignore_changes = [
+      metadata[0].annotations,
+    ]
+  }
+
+  autogenerate_revision_name = true
+  depends_on = [
+    time_sleep.wait_for_services
+  ]
+}
+
 module "circleci_parser" {
   source                         = "../fourkeys-circleci-parser"
   count                          = contains(var.parsers, "circleci") ? 1 : 0
   project_id                     = var.project_id
-  parser_container_url           = local.circleci_parser_url
-  region                         = var.region
+  push_endpoint                  = google_cloud_run_service.parser.status[0]["url"]
   fourkeys_service_account_email = google_service_account.fourkeys.email
-  enable_apis                    = var.enable_apis
-  depends_on = [
-    time_sleep.wait_for_services
-  ]
 }
 
 module "github_parser" {
   source                         = "../fourkeys-github-parser"
   count                          = contains(var.parsers, "github") ? 1 : 0
   project_id                     = var.project_id
-  parser_container_url           = local.github_parser_url
-  region                         = var.region
+  push_endpoint                  = google_cloud_run_service.parser.status[0]["url"]
   fourkeys_service_account_email = google_service_account.fourkeys.email
-  enable_apis                    = var.enable_apis
-  depends_on = [
-    time_sleep.wait_for_services
-  ]
 }
 
 module "gitlab_parser" {
   source                         = "../fourkeys-gitlab-parser"
   count                          = contains(var.parsers, "gitlab") ? 1 : 0
   project_id                     = var.project_id
-  parser_container_url           = local.gitlab_parser_url
-  region                         = var.region
+  push_endpoint                  = google_cloud_run_service.parser.status[0]["url"]
   fourkeys_service_account_email = google_service_account.fourkeys.email
-  enable_apis                    = var.enable_apis
-  depends_on = [
-    time_sleep.wait_for_services
-  ]
 }
 
 module "pagerduty_parser" {
   source                         = "../fourkeys-pagerduty-parser"
   count                          = contains(var.parsers, "pagerduty") ? 1 : 0
   project_id                     = var.project_id
-  parser_container_url           = local.pagerduty_parser_url
-  region                         = var.region
+  push_endpoint                  = google_cloud_run_service.parser.status[0]["url"]
   fourkeys_service_account_email = google_service_account.fourkeys.email
-  enable_apis                    = var.enable_apis
-  depends_on = [
-    time_sleep.wait_for_services
-  ]
 }
 
 module "tekton_parser" {
   source                         = "../fourkeys-tekton-parser"
   count                          = contains(var.parsers, "tekton") ? 1 : 0
   project_id                     = var.project_id
-  parser_container_url           = local.tekton_parser_url
-  region                         = var.region
+  push_endpoint                  = google_cloud_run_service.parser.status[0]["url"]
   fourkeys_service_account_email = google_service_account.fourkeys.email
-  enable_apis                    = var.enable_apis
-  depends_on = [
-    time_sleep.wait_for_services
-  ]
 }
 
 module "cloud_build_parser" {
   source                         = "../fourkeys-cloud-build-parser"
   count                          = contains(var.parsers, "cloud-build") ? 1 : 0
   project_id                     = var.project_id
-  parser_container_url           = local.cloud_build_parser_url
-  region                         = var.region
+  push_endpoint                  = google_cloud_run_service.parser.status[0]["url"]
   fourkeys_service_account_email = google_service_account.fourkeys.email
-  enable_apis                    = var.enable_apis
-  depends_on = [
-    time_sleep.wait_for_services
-  ]
-}
\ No newline at end of file
+}
@@ -4,7 +4,9 @@ source,
 event_type,
 JSON_EXTRACT_SCALAR(commit, '$.id') change_id,
 TIMESTAMP_TRUNC(TIMESTAMP(JSON_EXTRACT_SCALAR(commit, '$.timestamp')),second) as time_created,
-FROM four_keys.events_raw e,
+FROM four_keys.events e,
 UNNEST(JSON_EXTRACT_ARRAY(e.metadata, '$.commits')) as commit
 WHERE event_type = "push"
+# NOTE: is_default_branch is NULL for events recorded before branch rules were introduced
+AND IFNULL(e.is_default_branch, TRUE)
 GROUP BY 1,2,3,4
\ No newline at end of file
@@ -0,0 +1,6 @@
+# Events Table
+# NOTE: events_raw is written at-least-once, a redelivered Pub/Sub message is kept once
+SELECT *
+FROM four_keys.events_raw
+WHERE TRUE
+QUALIFY msg_id IS NULL OR ROW_NUMBER() OVER (PARTITION BY msg_id ORDER BY time_created) = 1
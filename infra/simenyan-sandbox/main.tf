@@ -6,5 +6,5 @@ module "fourkeys" {
   bigquery_region             = var.bigquery_region
   parsers                     = var.parsers
   event_handler_container_url = var.event_handler_container_url
-  github_parser_url           = var.parser_container_urls.github
+  parser_container_url        = var.parser_container_url
 }
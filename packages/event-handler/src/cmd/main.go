@@ -7,21 +7,37 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"cloud.google.com/go/pubsub"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/envelope"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/httpmw"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type environmentVariables struct {
 	projectID           string
 	githubWebhookSecret string
 	port                string
+	metricsPort         string
+	maxRequestBodyBytes int64
+	requestTimeout      time.Duration
 }
 
 var envVars environmentVariables
@@ -36,7 +52,36 @@ func init() {
 			envVars.port = "8000"
 		}
 	}
-	envVars.githubWebhookSecret = mustGetenv("GITHUB_WEBHOOK_SECRET")
+	{
+		// NOTE: /metrics is not served on PORT, which is the public webhook endpoint
+		port, ok := os.LookupEnv("METRICS_PORT")
+		if ok {
+			envVars.metricsPort = port
+		} else {
+			envVars.metricsPort = "9090"
+		}
+	}
+	{
+		// NOTE: GitHub caps webhook payloads at 25 MB
+		envVars.maxRequestBodyBytes = 25 << 20
+		if v, ok := os.LookupEnv("MAX_REQUEST_BODY_BYTES"); ok {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				panic("MAX_REQUEST_BODY_BYTES must be an integer: " + err.Error())
+			}
+			envVars.maxRequestBodyBytes = n
+		}
+	}
+	{
+		envVars.requestTimeout = 30 * time.Second
+		if v, ok := os.LookupEnv("REQUEST_TIMEOUT"); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				panic("REQUEST_TIMEOUT must be a duration: " + err.Error())
+			}
+			envVars.requestTimeout = d
+		}
+	}
 }
 
 func mustGetenv(k string) string {
@@ -51,33 +96,75 @@ func newContext() context.Context {
 	ctx = shared.WithLogger(ctx)
 	return ctx
 }
-func withLogger(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := shared.WithLogger(r.Context())
-		next.ServeHTTP(w, r.WithContext(ctx))
+
+func main() {
+	// NOTE: read here rather than in init, so that tests can set it
+	envVars.githubWebhookSecret = mustGetenv("GITHUB_WEBHOOK_SECRET")
+
+	ctx := newContext()
+	logger := shared.LoggerFromContext(ctx)
+
+	shutdown, err := telemetry.Setup(ctx, "event-handler")
+	if err != nil {
+		logger.Error(fmt.Sprintf("error setting up tracing: %s", err))
+		os.Exit(1)
 	}
-}
-func withRequestLog(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		logger := shared.LoggerFromContext(r.Context())
-
-		logger.Info("request received",
-			slog.String("method", r.Method),
-			slog.String("url", r.URL.String()),
-			slog.Any("header", r.Header),
-		)
-		next.ServeHTTP(w, r)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", otelhttp.NewHandler(httpmw.Wrap(withRequestMetrics(index), httpmw.Config{
+		ProjectID:    envVars.projectID,
+		MaxBodyBytes: envVars.maxRequestBodyBytes,
+		Timeout:      envVars.requestTimeout,
+	}), "webhook"))
+
+	signalContext, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go func() {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+		if err := listen(signalContext, envVars.metricsPort, metricsMux); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+	err = listen(signalContext, envVars.port, mux)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+	// NOTE: after the server, so that spans of the last requests are exported too
+	if err := shutdown(context.WithoutCancel(ctx)); err != nil {
+		logger.Error(fmt.Sprintf("error flushing traces: %s", err))
+	}
+	if err != nil {
+		os.Exit(1)
 	}
 }
 
-func main() {
-	ctx := newContext()
+// listen serves handler on port until ctx is done.
+func listen(ctx context.Context, port string, handler http.Handler) error {
 	logger := shared.LoggerFromContext(ctx)
-	http.HandleFunc("/", withLogger(withRequestLog(index)))
 
-	addr := ":" + envVars.port
-	logger.Info(fmt.Sprintf("listening on %s", addr))
-	http.ListenAndServe(addr, nil)
+	addr := ":" + port
+	server := &http.Server{Addr: addr, Handler: handler}
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info(fmt.Sprintf("listening on %s", addr))
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("error serving: %w", err)
+	case <-ctx.Done():
+	}
+
+	// NOTE: Cloud Run allows 10 seconds between SIGTERM and SIGKILL
+	shutdownContext, cancel := context.WithTimeout(context.WithoutCancel(ctx), 8*time.Second)
+	defer cancel()
+	logger.Info("shutting down")
+	if err := server.Shutdown(shutdownContext); err != nil {
+		return fmt.Errorf("error shutting down server: %w", err)
+	}
+	return nil
 }
 
 func index(w http.ResponseWriter, r *http.Request) {
@@ -104,12 +191,17 @@ var authorizedSources map[string]eventSource = map[string]eventSource{
 	},
 }
 
+var errSignatureMismatch = errors.New("signature mismatch")
+
 func verifyGithubSignature256(signature string, body []byte) bool {
 	h := hmac.New(sha256.New, []byte(envVars.githubWebhookSecret))
 	h.Write(body)
 	expectedMAC := h.Sum(nil)
 
 	signaturePrefix := "sha256="
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
 	signatureMAC, err := hex.DecodeString(signature[len(signaturePrefix):])
 	if err != nil {
 		return false
@@ -131,7 +223,7 @@ func verifyGithubSignature(signature string, body []byte) bool {
 }
 
 func indexPost(w http.ResponseWriter, r *http.Request) {
-	source := getSource(r.Header)
+	source := parser.DetectSource(r.Header)
 	if _, ok := authorizedSources[source]; !ok {
 		w.WriteHeader(http.StatusForbidden)
 		return
@@ -146,19 +238,31 @@ func indexPost(w http.ResponseWriter, r *http.Request) {
 		if v := r.Header.Get(authSource.signature); v != "" {
 			signature = v
 		} else {
+			signatureFailuresTotal.WithLabelValues(source).Inc()
 			w.WriteHeader(http.StatusForbidden)
+			return
 		}
 	}
 
 	b, err := io.ReadAll(r.Body)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	_, span := telemetry.Tracer().Start(r.Context(), "verify signature",
+		trace.WithAttributes(attribute.String("fourkeys.source", source)))
 	if !authSource.verification(signature, b) {
+		telemetry.End(span, errSignatureMismatch)
+		signatureFailuresTotal.WithLabelValues(source).Inc()
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
+	span.End()
 
 	pubsubHeaders := make(map[string][]string)
 	for k, v := range r.Header {
@@ -169,8 +273,16 @@ func indexPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logger := shared.LoggerFromContext(r.Context())
-	err = publishToPubsub(r.Context(), authSource, pubsubHeaders, b)
+	deliveryID := r.Header.Get("X-Github-Delivery")
+	if deliveryID == "" {
+		deliveryID = parser.UniqueID(b)
+	}
+	event := envelope.NewWebhookEvent(deliveryID, source, r.Header.Get("X-Github-Event"), pubsubHeaders, b)
+	start := time.Now()
+	err = publishToPubsub(r.Context(), authSource, event)
+	publishDuration.WithLabelValues(source).Observe(time.Since(start).Seconds())
 	if err != nil {
+		publishErrorsTotal.WithLabelValues(source).Inc()
 		logger.Error("error publishing to pubsub", slog.Any("error", err))
 		w.WriteHeader(http.StatusNoContent)
 		return
@@ -179,7 +291,16 @@ func indexPost(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func publishToPubsub(ctx context.Context, source eventSource, header map[string][]string, body []byte) error {
+func publishToPubsub(ctx context.Context, source eventSource, event *envelope.Event) (err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "publish "+source.name,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "gcp_pubsub"),
+			attribute.String("messaging.destination.name", source.name),
+			attribute.String("fourkeys.event_id", event.ID),
+		))
+	defer func() { telemetry.End(span, err) }()
+
 	logger := shared.LoggerFromContext(ctx)
 	projectID := envVars.projectID
 	if projectID == "" {
@@ -194,57 +315,36 @@ func publishToPubsub(ctx context.Context, source eventSource, header map[string]
 
 	{
 		var bodyMap any
-		err = json.Unmarshal(body, &bodyMap)
+		err = json.Unmarshal(event.Data, &bodyMap)
 		if err != nil {
 			logger.Error("error unmarshalling request", slog.Any("error", err))
 			return err
 		}
 
 		logger.Info("publishing to pubsub",
-			slog.Any("header", header),
+			slog.String("id", event.ID),
+			slog.Any("header", event.Headers),
 			slog.Any("body", bodyMap),
 		)
 	}
 
-	headersAttr, err := json.Marshal(header)
+	attrs, data, err := envelope.Encode(event)
 	if err != nil {
-		logger.Error("error marshalling headers", slog.Any("error", err))
+		logger.Error("error encoding event", slog.Any("error", err))
 		return err
 	}
+	telemetry.Inject(ctx, attrs)
 
 	res, err := client.Topic(source.name).Publish(ctx, &pubsub.Message{
-		Data:       body,
-		Attributes: map[string]string{"headers": string(headersAttr)},
+		Data:       data,
+		Attributes: attrs,
 	}).Get(ctx)
 	if err != nil {
 		logger.Error("error publishing to pubsub", slog.Any("error", err))
 		return err
 	}
 	logger.Info("published to pubsub", slog.String("messageID", res))
+	span.SetAttributes(attribute.String("messaging.message.id", res))
 
 	return nil
 }
-
-func getSource(header http.Header) string {
-	if _, ok := header["X-Gitlab-Event"]; ok {
-		return "gitlab"
-	}
-	if _, ok := header["Ce-Type"]; ok {
-		if v := header.Get("Ce-Type"); strings.Contains(v, "tekton") {
-			return "tekton"
-		}
-	}
-	if _, ok := header["User-Agent"]; ok {
-		if v := header.Get("User-Agent"); strings.Contains(v, "GitHub-Hookshot") {
-			return "github"
-		}
-	}
-	if _, ok := header["Circleci-Event-Type"]; ok {
-		return "circleci"
-	}
-	if _, ok := header["X-Pagerduty-Signature"]; ok {
-		return "pagerduty"
-	}
-
-	return header.Get("User-Agent")
-}
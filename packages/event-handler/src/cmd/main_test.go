@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestVerifyGithubSignature256(t *testing.T) {
+	envVars.githubWebhookSecret = "secret"
+	body := []byte(`{"zen":"Keep it logically awesome."}`)
+	h := hmac.New(sha256.New, []byte("secret"))
+	h.Write(body)
+	mac := hex.EncodeToString(h.Sum(nil))
+
+	for _, tt := range []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{"valid", "sha256=" + mac, true},
+		{"sha1 prefix", "sha1=" + mac, false},
+		{"no prefix", mac, false},
+		{"shorter than the prefix", "sha", false},
+		{"not hex", "sha256=zz", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyGithubSignature256(tt.signature, body); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexPostMissingSignature(t *testing.T) {
+	envVars.githubWebhookSecret = "secret"
+	failures := signatureFailuresTotal.WithLabelValues("github")
+	before := testutil.ToFloat64(failures)
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+	r.Header.Set("User-Agent", "GitHub-Hookshot/abc123")
+	r.Header.Set("X-Github-Event", "push")
+	w := httptest.NewRecorder()
+	indexPost(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status: %d", w.Code)
+	}
+	// NOTE: without the early return, the request would also fail verification and be counted twice
+	if d := testutil.ToFloat64(failures) - before; d != 1 {
+		t.Errorf("signature failures: %v", d)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/httpmw"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fourkeys_event_handler_requests_total",
+		Help: "Webhook requests by source and HTTP status code.",
+	}, []string{"source", "code"})
+	signatureFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fourkeys_event_handler_signature_failures_total",
+		Help: "Webhook requests rejected because of a missing or invalid signature.",
+	}, []string{"source"})
+	publishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "fourkeys_event_handler_publish_duration_seconds",
+		Help:    "Latency of publishing webhooks to Pub/Sub.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"source"})
+	publishErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fourkeys_event_handler_publish_errors_total",
+		Help: "Webhooks that could not be published to Pub/Sub.",
+	}, []string{"source"})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, signatureFailuresTotal, publishDuration, publishErrorsTotal)
+}
+
+// sourceLabel keeps the source label bounded: unauthorized sources fall back
+// to the User-Agent in parser.DetectSource.
+func sourceLabel(r *http.Request) string {
+	source := parser.DetectSource(r.Header)
+	if _, ok := authorizedSources[source]; !ok {
+		return "unknown"
+	}
+	return source
+}
+
+func withRequestMetrics(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httpmw.NewRecorder(w)
+		next.ServeHTTP(rec, r)
+		requestsTotal.WithLabelValues(sourceLabel(r), strconv.Itoa(rec.Status())).Inc()
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/oidc"
+)
+
+// pushVerifier is nil when Pub/Sub push authentication is disabled.
+var pushVerifier *oidc.Verifier
+
+func newPushVerifier() (*oidc.Verifier, error) {
+	if envVars.oidcAudience == "" && envVars.oidcEmail == "" {
+		return nil, nil
+	}
+	// NOTE: fail closed, any Google identity can get a token for the audience
+	if envVars.oidcEmail == "" {
+		return nil, errors.New("OIDC_SERVICE_ACCOUNT_EMAIL is required when OIDC_AUDIENCE is set")
+	}
+	return oidc.NewVerifier(oidc.Config{
+		Email:    envVars.oidcEmail,
+		JWKSURL:  envVars.oidcJWKSURL,
+		JWKSFile: envVars.oidcJWKSFile,
+	})
+}
+
+func withPushAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if pushVerifier == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logger := shared.LoggerFromContext(r.Context())
+		token, err := oidc.BearerToken(r)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("unauthorized push request: %s", err))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// NOTE: Pub/Sub uses the push endpoint URL as the audience unless one is configured
+		audience := envVars.oidcAudience
+		if audience == "" {
+			audience = "https://" + r.Host
+		}
+		if _, err := pushVerifier.Verify(r.Context(), token, audience); err != nil {
+			logger.Warn(fmt.Sprintf("unauthorized push request: %s", err))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
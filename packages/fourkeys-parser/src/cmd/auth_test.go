@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestNewPushVerifierRequiresEmail(t *testing.T) {
+	saved := envVars
+	t.Cleanup(func() { envVars = saved })
+
+	envVars.oidcAudience = "https://parser.example.com"
+	envVars.oidcEmail = ""
+	if _, err := newPushVerifier(); err == nil {
+		t.Errorf("expected error without OIDC_SERVICE_ACCOUNT_EMAIL")
+	}
+
+	envVars.oidcAudience = ""
+	if v, err := newPushVerifier(); v != nil || err != nil {
+		t.Errorf("expected authentication to be disabled, got %v, %v", v, err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/circleci"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/github"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/gitlab"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/pagerduty"
+)
+
+type config struct {
+	Github    github.Config    `json:"github"`
+	Gitlab    gitlab.Config    `json:"gitlab"`
+	Circleci  circleci.Config  `json:"circleci"`
+	Pagerduty pagerduty.Config `json:"pagerduty"`
+}
+
+func loadConfig(path string) (config, error) {
+	var c config
+	if path == "" {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("error reading config %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("error unmarshalling config %s: %w", path, err)
+	}
+
+	return c, nil
+}
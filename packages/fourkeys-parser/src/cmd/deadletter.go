@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/sink"
+)
+
+// NOTE: overwritten at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+const (
+	deadLetterTableID = "events_dead_letter"
+	// NOTE: used when neither DEAD_LETTER_FILE nor a BigQuery sink is configured
+	defaultDeadLetterFile = "events_dead_letter.jsonl"
+)
+
+type deadLetter struct {
+	MsgId         string    `bigquery:"msg_id" json:"msg_id"`
+	PublishTime   time.Time `bigquery:"publish_time" json:"publish_time"`
+	Subscription  string    `bigquery:"subscription" json:"subscription"`
+	Data          string    `bigquery:"data" json:"data"`
+	Headers       string    `bigquery:"headers" json:"headers"`
+	Attributes    string    `bigquery:"attributes" json:"attributes"`
+	Error         string    `bigquery:"error" json:"error"`
+	ErrorChain    []string  `bigquery:"error_chain" json:"error_chain"`
+	ParserVersion string    `bigquery:"parser_version" json:"parser_version"`
+	TimeFailed    time.Time `bigquery:"time_failed" json:"time_failed"`
+	// Resolution is set on the row reprocess appends once the message is handled
+	// (resolutionInserted or resolutionSkipped), so that it is not fetched again.
+	Resolution string `bigquery:"resolution" json:"resolution,omitempty"`
+}
+
+const (
+	resolutionInserted = "inserted"
+	resolutionSkipped  = "skipped"
+)
+
+func newDeadLetter(msg pubsubRequest, err error) *deadLetter {
+	return &deadLetter{
+		MsgId:         msg.Message.MessageId,
+		PublishTime:   msg.Message.PublishTime,
+		Subscription:  msg.Subscription,
+		Data:          msg.Message.Data,
+		Headers:       msg.Message.Attributes["headers"],
+		Attributes:    marshalAttributes(msg.Message.Attributes),
+		Error:         err.Error(),
+		ErrorChain:    errorChain(err),
+		ParserVersion: version,
+		TimeFailed:    time.Now(),
+	}
+}
+
+// resolved returns the row that marks d as handled.
+// NOTE: rows are appended rather than updated, streamed rows can not be updated for a while
+func (d *deadLetter) resolved(resolution string) *deadLetter {
+	r := *d
+	r.Resolution = resolution
+	return &r
+}
+
+func (d *deadLetter) pubsubRequest() pubsubRequest {
+	var msg pubsubRequest
+	msg.Message.MessageId = d.MsgId
+	msg.Message.PublishTime = d.PublishTime
+	msg.Message.Data = d.Data
+	if d.Attributes != "" {
+		// NOTE: an unreadable value leaves the message without attributes, which fails parsing again
+		_ = json.Unmarshal([]byte(d.Attributes), &msg.Message.Attributes)
+	} else if d.Headers != "" {
+		msg.Message.Attributes = map[string]string{"headers": d.Headers}
+	}
+	msg.Subscription = d.Subscription
+	return msg
+}
+
+func marshalAttributes(attrs map[string]string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// errorChain flattens err and everything it wraps, depth first.
+func errorChain(err error) []string {
+	var chain []string
+	var walk func(error)
+	walk = func(e error) {
+		if e == nil {
+			return
+		}
+		chain = append(chain, e.Error())
+		switch u := e.(type) {
+		case interface{ Unwrap() []error }:
+			for _, c := range u.Unwrap() {
+				walk(c)
+			}
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// deadLetterClient writes dead letters to BigQuery, it is opened once by openDeadLetters.
+var deadLetterClient *bigquery.Client
+
+// openDeadLetters opens deadLetterClient when dead letters go to BigQuery.
+func openDeadLetters(ctx context.Context) error {
+	if envVars.deadLetterFile != "" || !sink.IsBigQuery(envVars.sinkType) {
+		return nil
+	}
+	projectID := envVars.projectID
+	if projectID == "" {
+		projectID = bigquery.DetectProjectID
+	}
+	client, err := bigquery.NewClient(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	deadLetterClient = client
+	return nil
+}
+
+func closeDeadLetters() error {
+	if deadLetterClient == nil {
+		return nil
+	}
+	return deadLetterClient.Close()
+}
+
+func insertDeadLetter(ctx context.Context, d *deadLetter) error {
+	if envVars.deadLetterFile != "" {
+		return appendDeadLetters(envVars.deadLetterFile, d)
+	}
+	if !sink.IsBigQuery(envVars.sinkType) {
+		return appendDeadLetters(defaultDeadLetterFile, d)
+	}
+	return insertDeadLetterBigQuery(ctx, d)
+}
+
+func insertDeadLetterBigQuery(ctx context.Context, letters ...*deadLetter) error {
+	table := deadLetterClient.Dataset(envVars.dataset).Table(deadLetterTableID)
+	return table.Inserter().Put(ctx, letters)
+}
+
+var deadLetterFileMu sync.Mutex
+
+func appendDeadLetters(path string, letters ...*deadLetter) error {
+	deadLetterFileMu.Lock()
+	defer deadLetterFileMu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	for _, d := range letters {
+		if err := enc.Encode(d); err != nil {
+			return errors.Join(err, f.Close())
+		}
+	}
+	return f.Close()
+}
+
+func readDeadLetters(path string) ([]*deadLetter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var letters []*deadLetter
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var d deadLetter
+		if err := dec.Decode(&d); err != nil {
+			return nil, fmt.Errorf("error decoding dead letter %d: %w", len(letters)+1, err)
+		}
+		letters = append(letters, &d)
+	}
+	return letters, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestErrorChain(t *testing.T) {
+	root := errors.New("root")
+	err := fmt.Errorf("wrapped: %w", errors.Join(root, errors.New("other")))
+
+	got := errorChain(err)
+	want := []string{
+		"wrapped: root\nother",
+		"root\nother",
+		"root",
+		"other",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("errorChain() = %q, want %q", got, want)
+	}
+}
+
+func TestDeadLetterResolved(t *testing.T) {
+	d := &deadLetter{MsgId: "1", Error: "boom"}
+	r := d.resolved(resolutionSkipped)
+	if r.MsgId != "1" || r.Error != "boom" || r.Resolution != resolutionSkipped {
+		t.Errorf("resolved: %+v", r)
+	}
+	if d.Resolution != "" {
+		t.Errorf("the dead letter itself must not change: %+v", d)
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/envelope"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/httpmw"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/sink"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type environmentVariables struct {
+	port           string
+	metricsPort    string
+	projectID      string
+	configPath     string
+	parserSources  string
+	deadLetterFile string
+	oidcAudience   string
+	oidcEmail      string
+	oidcJWKSURL    string
+	oidcJWKSFile   string
+	sinkType       string
+	sinkDSN        string
+	dataset        string
+	batchMaxSize   int
+	batchMaxWait   time.Duration
+
+	maxRequestBodyBytes int64
+	requestTimeout      time.Duration
+
+	subscription                 string
+	workerNumGoroutines          int
+	workerMaxOutstandingMessages int
+	workerMaxExtension           time.Duration
+}
+
+var envVars environmentVariables
+
+func init() {
+	envVars.projectID = os.Getenv("PROJECT_ID")
+	envVars.configPath = os.Getenv("CONFIG_PATH")
+	envVars.parserSources = os.Getenv("PARSER_SOURCES")
+	envVars.deadLetterFile = os.Getenv("DEAD_LETTER_FILE")
+	envVars.oidcAudience = os.Getenv("OIDC_AUDIENCE")
+	envVars.oidcEmail = os.Getenv("OIDC_SERVICE_ACCOUNT_EMAIL")
+	envVars.oidcJWKSURL = os.Getenv("OIDC_JWKS_URL")
+	envVars.oidcJWKSFile = os.Getenv("OIDC_JWKS_FILE")
+	envVars.sinkType = os.Getenv("SINK_TYPE")
+	envVars.sinkDSN = os.Getenv("SINK_DSN")
+	{
+		dataset, ok := os.LookupEnv("BIGQUERY_DATASET")
+		if ok {
+			envVars.dataset = dataset
+		} else {
+			envVars.dataset = sink.DefaultDataset
+		}
+	}
+	envVars.batchMaxSize = intEnv("BATCH_MAX_SIZE")
+	envVars.batchMaxWait = durationEnv("BATCH_MAX_WAIT")
+	{
+		// NOTE: Pub/Sub messages are at most 10 MB, base64 encoded in push requests
+		envVars.maxRequestBodyBytes = 16 << 20
+		if _, ok := os.LookupEnv("MAX_REQUEST_BODY_BYTES"); ok {
+			envVars.maxRequestBodyBytes = int64(intEnv("MAX_REQUEST_BODY_BYTES"))
+		}
+	}
+	// NOTE: no timeout by default, the ack deadline of the subscription applies
+	envVars.requestTimeout = durationEnv("REQUEST_TIMEOUT")
+	{
+		subscription, ok := os.LookupEnv("SUBSCRIPTION")
+		if ok {
+			envVars.subscription = subscription
+		} else {
+			envVars.subscription = "github"
+		}
+	}
+	envVars.workerNumGoroutines = intEnv("WORKER_NUM_GOROUTINES")
+	envVars.workerMaxOutstandingMessages = intEnv("WORKER_MAX_OUTSTANDING_MESSAGES")
+	envVars.workerMaxExtension = durationEnv("WORKER_MAX_EXTENSION")
+	{
+		port, ok := os.LookupEnv("PORT")
+		if ok {
+			envVars.port = port
+		} else {
+			envVars.port = "8080"
+		}
+	}
+	{
+		// NOTE: /metrics is not served on PORT, which Pub/Sub push can reach
+		port, ok := os.LookupEnv("METRICS_PORT")
+		if ok {
+			envVars.metricsPort = port
+		} else {
+			envVars.metricsPort = "9090"
+		}
+	}
+}
+
+func intEnv(k string) int {
+	v, ok := os.LookupEnv(k)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		panic(k + " must be an integer: " + err.Error())
+	}
+	return n
+}
+
+func durationEnv(k string) time.Duration {
+	v, ok := os.LookupEnv(k)
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		panic(k + " must be a duration: " + err.Error())
+	}
+	return d
+}
+
+func newContext() context.Context {
+	ctx := context.Background()
+	ctx = shared.WithLogger(ctx)
+	return ctx
+}
+
+func main() {
+	mainContext := newContext()
+	logger := shared.LoggerFromContext(mainContext)
+
+	c, err := loadConfig(envVars.configPath)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error loading config: %s", err))
+		os.Exit(1)
+	}
+	r, err := newRegistry(c, envVars.parserSources)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error initializing parsers: %s", err))
+		os.Exit(1)
+	}
+	parsers = r
+	logger.Info(fmt.Sprintf("parsers: %s", strings.Join(r.Sources(), ",")))
+
+	s, err := sink.Open(mainContext, sink.Config{
+		Type:      envVars.sinkType,
+		DSN:       envVars.sinkDSN,
+		ProjectID: envVars.projectID,
+		Dataset:   envVars.dataset,
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("error opening sink: %s", err))
+		os.Exit(1)
+	}
+	if err := openDeadLetters(mainContext); err != nil {
+		logger.Error(fmt.Sprintf("error opening dead letters: %s", err))
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reprocess" {
+		eventSink = s
+		err := runReprocess(mainContext, os.Args[2:])
+		s.Close()
+		closeDeadLetters()
+		if err != nil {
+			logger.Error(fmt.Sprintf("error reprocessing dead letters: %s", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	shutdown, err := telemetry.Setup(mainContext, "fourkeys-parser")
+	if err != nil {
+		logger.Error(fmt.Sprintf("error setting up tracing: %s", err))
+		os.Exit(1)
+	}
+
+	batcher := sink.NewBatcher(&meteredSink{Sink: s, name: sinkName(envVars.sinkType)}, sink.BatchConfig{
+		MaxSize: envVars.batchMaxSize,
+		MaxWait: envVars.batchMaxWait,
+	})
+	eventSink = batcher
+
+	signalContext, stop := signal.NotifyContext(mainContext, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsHandler())
+		if err := listen(signalContext, envVars.metricsPort, mux); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		err = runWorker(signalContext)
+	} else {
+		err = serve(signalContext)
+	}
+	if err != nil {
+		logger.Error(err.Error())
+	}
+
+	closeErr := batcher.Close()
+	closeDeadLetters()
+	// NOTE: after the batcher, so that spans of the last inserts are exported too
+	if err := shutdown(context.WithoutCancel(mainContext)); err != nil {
+		logger.Error(fmt.Sprintf("error flushing traces: %s", err))
+	}
+	if closeErr != nil {
+		logger.Error(fmt.Sprintf("error flushing sink: %s", closeErr))
+		os.Exit(1)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// serve handles Pub/Sub push requests until ctx is done.
+func serve(ctx context.Context) error {
+	logger := shared.LoggerFromContext(ctx)
+
+	v, err := newPushVerifier()
+	if err != nil {
+		return fmt.Errorf("error initializing push verifier: %w", err)
+	}
+	if v == nil {
+		logger.Warn("OIDC_AUDIENCE and OIDC_SERVICE_ACCOUNT_EMAIL are not set, push requests are not authenticated")
+	}
+	pushVerifier = v
+
+	mux := http.NewServeMux()
+	mux.Handle("/", httpmw.Wrap(withRequestMetrics(withPushAuth(index)), httpmw.Config{
+		ProjectID:    envVars.projectID,
+		MaxBodyBytes: envVars.maxRequestBodyBytes,
+		Timeout:      envVars.requestTimeout,
+	}))
+	return listen(ctx, envVars.port, mux)
+}
+
+// listen serves handler on port until ctx is done.
+func listen(ctx context.Context, port string, handler http.Handler) error {
+	logger := shared.LoggerFromContext(ctx)
+
+	addr := ":" + port
+	server := &http.Server{Addr: addr, Handler: handler}
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info(fmt.Sprintf("listening on %s", addr))
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("error serving: %w", err)
+	case <-ctx.Done():
+	}
+
+	// NOTE: Cloud Run allows 10 seconds between SIGTERM and SIGKILL
+	shutdownContext, cancel := context.WithTimeout(context.WithoutCancel(ctx), 8*time.Second)
+	defer cancel()
+	logger.Info("shutting down")
+	if err := server.Shutdown(shutdownContext); err != nil {
+		return fmt.Errorf("error shutting down server: %w", err)
+	}
+	return nil
+}
+
+func index(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		indexPost(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func indexPost(w http.ResponseWriter, r *http.Request) {
+	logger := shared.LoggerFromContext(r.Context())
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error reading request body: %s", err))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var msg pubsubRequest
+	err = json.Unmarshal(body, &msg)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error unmarshalling request: %s", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := handleMessage(r.Context(), msg); err != nil {
+		// NOTE: nack so that Pub/Sub redelivers the message
+		logger.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleMessage parses msg and writes it to the sink.
+// An error means the message has to be redelivered.
+func handleMessage(ctx context.Context, msg pubsubRequest) (err error) {
+	// NOTE: continues the trace started by the publisher, see event-handler
+	ctx, span := telemetry.Tracer().Start(telemetry.Extract(ctx, msg.Message.Attributes), "process message",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "gcp_pubsub"),
+			attribute.String("messaging.message.id", msg.Message.MessageId),
+			attribute.String("messaging.destination.subscription.name", msg.Subscription),
+		))
+	defer func() { telemetry.End(span, err) }()
+	logger := shared.LoggerFromContext(ctx)
+
+	event, err := parseMessage(ctx, msg)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("error processing event: %s", err))
+		if err := insertDeadLetter(ctx, newDeadLetter(msg, err)); err != nil {
+			return fmt.Errorf("error inserting dead letter: %w", err)
+		}
+		return nil
+	}
+	if event != nil {
+		insertContext, insertSpan := telemetry.Tracer().Start(ctx, "insert")
+		err := eventSink.Insert(insertContext, event)
+		telemetry.End(insertSpan, err)
+		if err != nil {
+			return fmt.Errorf("error inserting into sink: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func parseMessage(ctx context.Context, msg pubsubRequest) (record *shared.EventRecord, err error) {
+	logger := shared.LoggerFromContext(ctx)
+
+	var (
+		parserSource, eventType string
+		skip                    parser.Skip
+	)
+	defer func() {
+		observeParse(parserSource, eventType, skip, record, err)
+	}()
+
+	data, err := base64.StdEncoding.DecodeString(msg.Message.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding data: %w", err)
+	}
+
+	var (
+		headers map[string][]string
+		source  string
+		subject string
+	)
+	event, err := msg.envelope(data)
+	switch {
+	case err == nil:
+		headers, source, subject, data = event.Headers, event.Source, event.Subject, event.Data
+	case errors.Is(err, envelope.ErrNotCloudEvent):
+		if headers, err = msg.headers(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("error decoding envelope: %w", err)
+	}
+
+	logger.Info("parsed",
+		slog.String("subscription", msg.Subscription),
+		slog.String("eventSource", source),
+		slog.Any("attr", headers),
+		slog.String("metadata", string(data)),
+	)
+
+	eventType = eventTypeLabel(subject, headers)
+	p, err := resolveParser(msg.Subscription, source, headers)
+	if err != nil {
+		return nil, err
+	}
+	parserSource = p.Source()
+	parseContext, parseSpan := telemetry.Tracer().Start(ctx, "parse", trace.WithAttributes(
+		attribute.String("fourkeys.source", parserSource),
+		attribute.String("fourkeys.event_type", eventType),
+	))
+	record, err = p.Parse(parser.WithSkip(parseContext, &skip), headers, data)
+	telemetry.End(parseSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s event: %w", p.Source(), err)
+	}
+	if record != nil {
+		record.MsgId = msg.Message.MessageId
+	}
+	return record, nil
+}
+
+// resolveParser prefers the subscription, then the envelope source, then the webhook headers.
+func resolveParser(subscription, source string, headers map[string][]string) (parser.Parser, error) {
+	if subscription != "" {
+		if p, ok := parsers.Lookup(path.Base(subscription)); ok {
+			return p, nil
+		}
+	}
+	if source != "" {
+		if p, ok := parsers.Lookup(source); ok {
+			return p, nil
+		}
+	}
+	return parsers.Resolve(subscription, headers)
+}
+
+type pubsubRequest struct {
+	Message struct {
+		MessageId   string            `json:"messageId"`
+		PublishTime time.Time         `json:"publishTime"`
+		Data        string            `json:"data"`
+		Attributes  map[string]string `json:"attributes"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+// envelope decodes the CloudEvent carried by the message, either in binary mode
+// (ce-* attributes) or in structured mode (application/cloudevents+json data).
+func (msg pubsubRequest) envelope(data []byte) (*envelope.Event, error) {
+	if msg.Message.Attributes["content-type"] == envelope.ContentTypeStructured {
+		return envelope.UnmarshalStructured(data)
+	}
+	return envelope.Decode(msg.Message.Attributes, data)
+}
+
+// headers returns the webhook headers stored in the "headers" attribute by producers
+// that predate the envelope.
+// Messages published by other producers (e.g. Cloud Build) carry plain attributes instead.
+func (msg pubsubRequest) headers() (map[string][]string, error) {
+	if raw, ok := msg.Message.Attributes["headers"]; ok {
+		var headers map[string][]string
+		if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling headers: %w", err)
+		}
+		return headers, nil
+	}
+
+	headers := make(map[string][]string, len(msg.Message.Attributes))
+	for k, v := range msg.Message.Attributes {
+		headers[k] = []string{v}
+	}
+	return headers, nil
+}
+
+var eventSink sink.Sink
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/sink"
+)
+
+const (
+	unknownLabel = "unknown"
+	otherLabel   = "other"
+)
+
+// eventTypeLabels keeps the event_type label bounded: the types each source
+// sends are known, anything else is counted as otherLabel.
+var eventTypeLabels = map[string][]string{
+	"github": {
+		"push", "pull_request", "pull_request_review", "pull_request_review_comment",
+		"issues", "issue_comment", "check_run", "check_suite", "status", "deployment",
+		"deployment_status", "release", "projects_v2_item", "create", "delete", "fork",
+		"ping", "star", "watch", "workflow_job", "workflow_run",
+	},
+	"gitlab": {
+		"Push Hook", "Tag Push Hook", "Merge Request Hook", "Note Hook", "Issue Hook",
+		"Pipeline Hook", "Job Hook", "Deployment Hook", "Release Hook",
+		"push", "tag_push", "merge_request", "note", "issue", "pipeline", "job", "build",
+		"deployment", "release",
+	},
+	"circleci":    {"workflow-completed", "job-completed", "ping"},
+	"cloud_build": {"build"},
+	"tekton": {
+		"dev.tekton.event.pipelinerun.started.v1", "dev.tekton.event.pipelinerun.running.v1",
+		"dev.tekton.event.pipelinerun.successful.v1", "dev.tekton.event.pipelinerun.failed.v1",
+		"dev.tekton.event.taskrun.started.v1", "dev.tekton.event.taskrun.running.v1",
+		"dev.tekton.event.taskrun.successful.v1", "dev.tekton.event.taskrun.failed.v1",
+	},
+	"pagerduty": {
+		"incident.triggered", "incident.acknowledged", "incident.unacknowledged",
+		"incident.resolved", "incident.reopened", "incident.reassigned", "incident.escalated",
+		"incident.delegated", "incident.annotated", "incident.priority_updated",
+		"incident.responder.added", "incident.responder.replied", "incident.status_update_published",
+	},
+}
+
+var knownEventTypes = func() map[string]map[string]bool {
+	known := map[string]map[string]bool{}
+	for source, types := range eventTypeLabels {
+		known[source] = map[string]bool{}
+		for _, t := range types {
+			known[source][t] = true
+		}
+	}
+	return known
+}()
+
+// boundedEventType returns eventType if source is known to send it, otherLabel otherwise.
+func boundedEventType(source, eventType string) string {
+	if eventType == "" {
+		return unknownLabel
+	}
+	if !knownEventTypes[source][eventType] {
+		return otherLabel
+	}
+	return eventType
+}
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fourkeys_parser_requests_total",
+		Help: "Pub/Sub push requests by HTTP status code.",
+	}, []string{"code"})
+	messagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fourkeys_parser_messages_total",
+		Help: "Parsed messages by source and result (parsed, skipped, unsupported or error).",
+	}, []string{"source", "result"})
+	parseErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fourkeys_parser_parse_errors_total",
+		Help: "Messages that could not be parsed, by source and event type.",
+	}, []string{"source", "event_type"})
+	unsupportedEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fourkeys_parser_unsupported_events_total",
+		Help: "Events skipped because the parser does not support their type.",
+	}, []string{"source", "event_type"})
+	sinkInsertDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "fourkeys_parser_sink_insert_duration_seconds",
+		Help:    "Latency of writes to the sink, one per batch.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"sink"})
+	sinkInsertErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fourkeys_parser_sink_insert_errors_total",
+		Help: "Failed writes to the sink.",
+	}, []string{"sink"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		requestsTotal,
+		messagesTotal,
+		parseErrorsTotal,
+		unsupportedEventsTotal,
+		sinkInsertDuration,
+		sinkInsertErrorsTotal,
+	)
+}
+
+func withRequestMetrics(next http.HandlerFunc) http.HandlerFunc {
+	return promhttp.InstrumentHandlerCounter(requestsTotal, next)
+}
+
+// metricsHandler serves /metrics on METRICS_PORT, apart from the push endpoint.
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// observeParse counts the outcome of parsing a message.
+func observeParse(source, eventType string, skip parser.Skip, record *shared.EventRecord, err error) {
+	if source == "" {
+		source = unknownLabel
+	}
+	switch {
+	case err != nil:
+		messagesTotal.WithLabelValues(source, "error").Inc()
+		parseErrorsTotal.WithLabelValues(source, boundedEventType(source, eventType)).Inc()
+	case record != nil:
+		messagesTotal.WithLabelValues(source, "parsed").Inc()
+	case skip.Reason == parser.SkipUnsupported:
+		messagesTotal.WithLabelValues(source, "unsupported").Inc()
+		unsupportedEventsTotal.WithLabelValues(source, boundedEventType(source, skip.EventType)).Inc()
+	default:
+		messagesTotal.WithLabelValues(source, "skipped").Inc()
+	}
+}
+
+// eventTypeHeaders name the event type in the webhook headers of each source.
+var eventTypeHeaders = []string{"X-Github-Event", "X-Gitlab-Event", "Circleci-Event-Type", "Ce-Type"}
+
+// eventTypeLabel guesses the event type of a message the parser failed on.
+func eventTypeLabel(subject string, headers map[string][]string) string {
+	if subject != "" {
+		return subject
+	}
+	for _, k := range eventTypeHeaders {
+		if v := parser.Header(headers, k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sinkName(typ string) string {
+	if typ == "" {
+		return sink.TypeBigQuery
+	}
+	return typ
+}
+
+// meteredSink records the latency and errors of the writes to a sink.
+type meteredSink struct {
+	sink.Sink
+	name string
+}
+
+func (s *meteredSink) Insert(ctx context.Context, records ...*shared.EventRecord) error {
+	start := time.Now()
+	err := s.Sink.Insert(ctx, records...)
+	sinkInsertDuration.WithLabelValues(s.name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		sinkInsertErrorsTotal.WithLabelValues(s.name).Inc()
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+)
+
+func TestObserveParse(t *testing.T) {
+	// NOTE: the counters are global, so only their deltas are checked
+	counters := map[string]prometheus.Counter{
+		"parse errors":          parseErrorsTotal.WithLabelValues("github", "push"),
+		"unsupported events":    unsupportedEventsTotal.WithLabelValues("github", "fork"),
+		"unknown source errors": messagesTotal.WithLabelValues(unknownLabel, "error"),
+		"other event types":     unsupportedEventsTotal.WithLabelValues("github", otherLabel),
+	}
+	before := map[string]float64{}
+	for name, c := range counters {
+		before[name] = testutil.ToFloat64(c)
+	}
+
+	observeParse("github", "push", parser.Skip{}, nil, errors.New("boom"))
+	observeParse("github", "fork", parser.Skip{EventType: "fork", Reason: parser.SkipUnsupported}, nil, nil)
+	observeParse("", "", parser.Skip{}, nil, errors.New("no parser"))
+	observeParse("github", "", parser.Skip{EventType: "random-1234", Reason: parser.SkipUnsupported}, nil, nil)
+
+	for name, c := range counters {
+		if d := testutil.ToFloat64(c) - before[name]; d != 1 {
+			t.Errorf("%s: %v", name, d)
+		}
+	}
+}
+
+func TestBoundedEventType(t *testing.T) {
+	for _, tt := range []struct{ source, eventType, want string }{
+		{"github", "push", "push"},
+		{"github", "Push Hook", otherLabel},
+		{"gitlab", "Push Hook", "Push Hook"},
+		{"unknown", "push", otherLabel},
+		{"github", "", unknownLabel},
+	} {
+		if got := boundedEventType(tt.source, tt.eventType); got != tt.want {
+			t.Errorf("boundedEventType(%q, %q) = %q, want %q", tt.source, tt.eventType, got, tt.want)
+		}
+	}
+}
+
+func TestEventTypeLabel(t *testing.T) {
+	if got := eventTypeLabel("", map[string][]string{"X-Gitlab-Event": {"Push Hook"}}); got != "Push Hook" {
+		t.Errorf("got %q", got)
+	}
+	if got := eventTypeLabel("push", map[string][]string{"X-Github-Event": {"ignored"}}); got != "push" {
+		t.Errorf("subject must win, got %q", got)
+	}
+}
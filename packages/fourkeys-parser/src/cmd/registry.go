@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/circleci"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/cloudbuild"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/github"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/gitlab"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/pagerduty"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/tekton"
+)
+
+var parsers *parser.Registry
+
+// newRegistry registers a parser for every source, or only for those listed in
+// PARSER_SOURCES (comma separated) when it is set.
+func newRegistry(c config, sources string) (*parser.Registry, error) {
+	githubParser, err := github.New(c.Github)
+	if err != nil {
+		return nil, fmt.Errorf("github: %w", err)
+	}
+	gitlabParser, err := gitlab.New(c.Gitlab)
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: %w", err)
+	}
+	circleciParser, err := circleci.New(c.Circleci)
+	if err != nil {
+		return nil, fmt.Errorf("circleci: %w", err)
+	}
+	pagerdutyParser, err := pagerduty.New(c.Pagerduty)
+	if err != nil {
+		return nil, fmt.Errorf("pagerduty: %w", err)
+	}
+	all := []parser.Parser{
+		githubParser,
+		gitlabParser,
+		circleciParser,
+		pagerdutyParser,
+		tekton.New(),
+		cloudbuild.New(),
+	}
+
+	enabled := map[string]bool{}
+	for _, s := range strings.Split(sources, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			enabled[s] = true
+		}
+	}
+
+	r := parser.NewRegistry()
+	for _, p := range all {
+		if len(enabled) > 0 && !enabled[p.Source()] {
+			continue
+		}
+		delete(enabled, p.Source())
+		r.Register(p)
+	}
+	for s := range enabled {
+		return nil, fmt.Errorf("unknown parser source %q", s)
+	}
+
+	// NOTE: the Cloud Build topic and subscription are not named after the source
+	r.Alias("cloudbuild", "cloud_build")
+	r.Alias("cloud-builds", "cloud_build")
+
+	return r, nil
+}
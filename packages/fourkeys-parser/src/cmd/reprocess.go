@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"google.golang.org/api/iterator"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+// runReprocess feeds dead letters through the current parser again.
+//
+//	main reprocess [-file dead_letters.jsonl] [-out still_failing.jsonl] [-dry-run]
+//
+// Without -file, dead letters whose msg_id is neither in events_raw nor resolved are read
+// from BigQuery, and the handled ones are marked as resolved.
+func runReprocess(ctx context.Context, args []string) error {
+	logger := shared.LoggerFromContext(ctx)
+
+	fs := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	file := fs.String("file", "", "read dead letters from this JSONL file instead of BigQuery")
+	out := fs.String("out", "", "append dead letters that still fail to this JSONL file")
+	dryRun := fs.Bool("dry-run", false, "parse only, do not insert into the sink")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var (
+		letters []*deadLetter
+		err     error
+	)
+	if *file != "" {
+		letters, err = readDeadLetters(*file)
+	} else {
+		letters, err = queryDeadLetters(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("error loading dead letters: %w", err)
+	}
+
+	var (
+		failed, resolved  []*deadLetter
+		inserted, skipped int
+		insertErr         error
+	)
+	for _, d := range letters {
+		event, err := parseMessage(ctx, d.pubsubRequest())
+		if err != nil {
+			logger.Warn(fmt.Sprintf("still failing: %s", err), slog.String("msgId", d.MsgId))
+			failed = append(failed, newDeadLetter(d.pubsubRequest(), err))
+			continue
+		}
+		if event == nil {
+			skipped++
+			resolved = append(resolved, d.resolved(resolutionSkipped))
+			continue
+		}
+		if *dryRun {
+			inserted++
+			continue
+		}
+		if err := eventSink.Insert(ctx, event); err != nil {
+			insertErr = fmt.Errorf("error inserting %s into sink: %w", d.MsgId, err)
+			break
+		}
+		inserted++
+		resolved = append(resolved, d.resolved(resolutionInserted))
+	}
+
+	// NOTE: a file is not updated, only the still failing dead letters are written to -out
+	if *file == "" && !*dryRun && len(resolved) > 0 {
+		if err := insertDeadLetterBigQuery(ctx, resolved...); err != nil {
+			return errors.Join(insertErr, fmt.Errorf("error resolving dead letters: %w", err))
+		}
+	}
+	if insertErr != nil {
+		return insertErr
+	}
+
+	logger.Info("reprocessed dead letters",
+		slog.Int("total", len(letters)),
+		slog.Int("inserted", inserted),
+		slog.Int("skipped", skipped),
+		slog.Int("failed", len(failed)),
+		slog.Bool("dryRun", *dryRun),
+	)
+
+	if *out != "" && len(failed) > 0 {
+		return appendDeadLetters(*out, failed...)
+	}
+	if len(failed) > 0 {
+		return errors.New("some dead letters could not be reprocessed")
+	}
+	return nil
+}
+
+func queryDeadLetters(ctx context.Context) ([]*deadLetter, error) {
+	if deadLetterClient == nil {
+		return nil, errors.New("dead letters are not stored in BigQuery, use -file")
+	}
+	q := deadLetterClient.Query(fmt.Sprintf(`
+SELECT d.*
+FROM %[1]s.%[2]s d
+WHERE d.msg_id NOT IN (SELECT msg_id FROM %[1]s.events_raw WHERE msg_id IS NOT NULL)
+AND d.msg_id NOT IN (SELECT msg_id FROM %[1]s.%[2]s WHERE msg_id IS NOT NULL AND resolution != "")
+QUALIFY ROW_NUMBER() OVER (PARTITION BY d.msg_id ORDER BY d.time_failed DESC) = 1
+ORDER BY d.publish_time`, envVars.dataset, deadLetterTableID))
+	it, err := q.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var letters []*deadLetter
+	for {
+		var d deadLetter
+		err := it.Next(&d)
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, &d)
+	}
+	return letters, nil
+}
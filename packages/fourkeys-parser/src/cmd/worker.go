@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+// runWorker pulls messages from envVars.subscription until ctx is done.
+// Messages are acked once written and nacked on error, like push responses.
+func runWorker(ctx context.Context) error {
+	logger := shared.LoggerFromContext(ctx)
+
+	projectID := envVars.projectID
+	if projectID == "" {
+		projectID = pubsub.DetectProjectID
+	}
+	client, err := pubsub.NewClient(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("error creating pubsub client: %w", err)
+	}
+	defer client.Close()
+
+	sub := client.Subscription(envVars.subscription)
+	if envVars.workerNumGoroutines > 0 {
+		sub.ReceiveSettings.NumGoroutines = envVars.workerNumGoroutines
+	}
+	if envVars.workerMaxOutstandingMessages != 0 {
+		sub.ReceiveSettings.MaxOutstandingMessages = envVars.workerMaxOutstandingMessages
+	}
+	if envVars.workerMaxExtension != 0 {
+		sub.ReceiveSettings.MaxExtension = envVars.workerMaxExtension
+	}
+
+	logger.Info(fmt.Sprintf("pulling from %s", envVars.subscription),
+		slog.Int("numGoroutines", sub.ReceiveSettings.NumGoroutines),
+		slog.Int("maxOutstandingMessages", sub.ReceiveSettings.MaxOutstandingMessages),
+		slog.Duration("maxExtension", sub.ReceiveSettings.MaxExtension),
+	)
+	err = sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		ctx = shared.SetLogger(ctx, logger.With(slog.String("messageId", m.ID)))
+		if err := handleMessage(ctx, pullRequest(envVars.subscription, m)); err != nil {
+			logger.Error(err.Error(), slog.String("messageId", m.ID))
+			m.Nack()
+			return
+		}
+		m.Ack()
+	})
+	if err != nil {
+		return fmt.Errorf("error receiving from %s: %w", envVars.subscription, err)
+	}
+	return nil
+}
+
+// pullRequest converts a pulled message to the push request format.
+func pullRequest(subscription string, m *pubsub.Message) pubsubRequest {
+	var msg pubsubRequest
+	msg.Message.MessageId = m.ID
+	msg.Message.PublishTime = m.PublishTime
+	msg.Message.Data = base64.StdEncoding.EncodeToString(m.Data)
+	msg.Message.Attributes = m.Attributes
+	msg.Subscription = subscription
+	return msg
+}
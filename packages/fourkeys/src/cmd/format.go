@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+)
+
+const (
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+)
+
+func validFormat(f string) bool {
+	return f == formatJSON || f == formatCSV || f == formatMarkdown
+}
+
+func writeReport(w io.Writer, format string, rows []metrics.Row, withRepo bool) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if rows == nil {
+			rows = []metrics.Row{}
+		}
+		return enc.Encode(rows)
+	case formatCSV:
+		return writeCSV(w, rows)
+	default:
+		return writeMarkdown(w, rows, withRepo)
+	}
+}
+
+var csvHeader = []string{
+	"period_start", "period_end", "repository",
+	"changes", "deployments", "days_with_deployments", "deployments_per_day",
+	"lead_time_samples", "lead_time_median_seconds", "lead_time_p90_seconds",
+	"failed_deployments", "change_failure_rate",
+	"incidents", "resolved_incidents", "time_to_restore_median_seconds", "time_to_restore_p90_seconds",
+	"tier", "deployment_frequency_tier", "lead_time_tier", "change_failure_rate_tier", "time_to_restore_tier",
+}
+
+func writeCSV(w io.Writer, rows []metrics.Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		c := r.Classification
+		record := []string{
+			r.PeriodStart.Format(time.RFC3339), r.PeriodEnd.Format(time.RFC3339), r.Repository,
+			strconv.Itoa(r.Changes), strconv.Itoa(r.Deployments), strconv.Itoa(r.DaysWithDeployments), formatFloat(r.DeploymentsPerDay),
+			strconv.Itoa(r.LeadTimeSamples), strconv.FormatInt(r.LeadTimeMedianSeconds, 10), strconv.FormatInt(r.LeadTimeP90Seconds, 10),
+			strconv.Itoa(r.FailedDeployments), formatFloat(r.ChangeFailureRate),
+			strconv.Itoa(r.Incidents), strconv.Itoa(r.ResolvedIncidents),
+			strconv.FormatInt(r.TimeToRestoreMedianSeconds, 10), strconv.FormatInt(r.TimeToRestoreP90Seconds, 10),
+			string(c.Overall), string(c.DeploymentFrequency.Tier), string(c.LeadTime.Tier),
+			string(c.ChangeFailureRate.Tier), string(c.TimeToRestore.Tier),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeMarkdown(w io.Writer, rows []metrics.Row, withRepo bool) error {
+	header := []string{"Period"}
+	if withRepo {
+		header = append(header, "Repository")
+	}
+	header = append(header, "Deployments", "Deploys/day", "Lead time (median)", "Lead time (p90)",
+		"Change failure rate", "Time to restore (median)", "Incidents", "Tier")
+
+	var b strings.Builder
+	writeMarkdownRow(&b, header)
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	writeMarkdownRow(&b, separator)
+
+	for _, r := range rows {
+		cells := []string{formatPeriod(r.PeriodStart, r.PeriodEnd)}
+		if withRepo {
+			repo := r.Repository
+			if repo == "" {
+				repo = "(none)"
+			}
+			cells = append(cells, repo)
+		}
+		cfr := "-"
+		if r.Deployments > 0 {
+			cfr = fmt.Sprintf("%.1f%%", r.ChangeFailureRate*100)
+		}
+		cells = append(cells,
+			strconv.Itoa(r.Deployments),
+			fmt.Sprintf("%.2f", r.DeploymentsPerDay),
+			formatDuration(r.LeadTimeMedianSeconds, r.LeadTimeSamples),
+			formatDuration(r.LeadTimeP90Seconds, r.LeadTimeSamples),
+			cfr,
+			formatDuration(r.TimeToRestoreMedianSeconds, r.ResolvedIncidents),
+			strconv.Itoa(r.Incidents),
+			string(r.Classification.Overall),
+		)
+		writeMarkdownRow(&b, cells)
+	}
+
+	b.WriteString("\n")
+	for _, r := range rows {
+		c := r.Classification
+		label := formatPeriod(r.PeriodStart, r.PeriodEnd)
+		if withRepo {
+			label += " " + r.Repository
+		}
+		fmt.Fprintf(&b, "- %s: **%s**\n", strings.TrimSpace(label), c.Overall)
+		fmt.Fprintf(&b, "  - deployment frequency: %s (%s)\n", c.DeploymentFrequency.Tier, c.DeploymentFrequency.Reason)
+		fmt.Fprintf(&b, "  - lead time: %s (%s)\n", c.LeadTime.Tier, c.LeadTime.Reason)
+		fmt.Fprintf(&b, "  - change failure rate: %s (%s)\n", c.ChangeFailureRate.Tier, c.ChangeFailureRate.Reason)
+		fmt.Fprintf(&b, "  - time to restore: %s (%s)\n", c.TimeToRestore.Tier, c.TimeToRestore.Reason)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownRow(b *strings.Builder, cells []string) {
+	b.WriteString("| ")
+	for i, c := range cells {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		b.WriteString(strings.ReplaceAll(c, "|", `\|`))
+	}
+	b.WriteString(" |\n")
+}
+
+func formatPeriod(from, to time.Time) string {
+	last := to.Add(-time.Nanosecond)
+	if from.Format(time.DateOnly) == last.Format(time.DateOnly) {
+		return from.Format(time.DateOnly)
+	}
+	return from.Format(time.DateOnly) + " - " + last.Format(time.DateOnly)
+}
+
+// formatDuration renders seconds as e.g. "2d 3h", "4h 5m" or "12m", and "-" without samples.
+func formatDuration(seconds int64, samples int) string {
+	if samples == 0 {
+		return "-"
+	}
+	d := time.Duration(seconds) * time.Second
+	days := int64(d / (24 * time.Hour))
+	hours := int64(d % (24 * time.Hour) / time.Hour)
+	minutes := int64(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
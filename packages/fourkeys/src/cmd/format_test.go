@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+)
+
+func TestWriteReport(t *testing.T) {
+	rows := []metrics.Row{{
+		PeriodStart:           time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:             time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		Repository:            "org/app",
+		Deployments:           4,
+		DeploymentsPerDay:     4.0 / 7,
+		LeadTimeSamples:       3,
+		LeadTimeMedianSeconds: 26 * 3600,
+		LeadTimeP90Seconds:    90 * 60,
+		FailedDeployments:     1,
+		ChangeFailureRate:     0.25,
+		Classification: metrics.Classification{
+			Overall:             metrics.TierMedium,
+			DeploymentFrequency: metrics.MetricTier{Tier: metrics.TierHigh, Reason: "0.57 deployments/day, at least 0.14"},
+			LeadTime:            metrics.MetricTier{Tier: metrics.TierHigh, Reason: "median lead time 26h0m0s, within 168h0m0s"},
+			ChangeFailureRate:   metrics.MetricTier{Tier: metrics.TierHigh, Reason: "25.0% of deployments failed, at most 30.0%"},
+			TimeToRestore:       metrics.MetricTier{Tier: metrics.TierUnknown, Reason: "no resolved incidents"},
+		},
+	}}
+
+	var md bytes.Buffer
+	if err := writeReport(&md, formatMarkdown, rows, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| 2024-01-01 - 2024-01-07 | org/app | 4 | 0.57 | 1d 2h | 1h 30m | 25.0% | - | 0 | medium |\n",
+		"- 2024-01-01 - 2024-01-07 org/app: **medium**\n",
+		"  - change failure rate: high (25.0% of deployments failed, at most 30.0%)\n",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown:\n%s\nwant:\n%s", md.String(), want)
+		}
+	}
+
+	var csv bytes.Buffer
+	if err := writeReport(&csv, formatCSV, rows, true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "2024-01-01T00:00:00Z,2024-01-08T00:00:00Z,org/app,0,4,") {
+		t.Errorf("csv:\n%s", csv.String())
+	}
+
+	var empty bytes.Buffer
+	if err := writeReport(&empty, formatJSON, nil, false); err != nil || strings.TrimSpace(empty.String()) != "[]" {
+		t.Errorf("json: %s, %v", empty.String(), err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+const usage = `usage: fourkeys <command> [flags]
+
+commands:
+  report    compute the four keys from stored events
+
+run "fourkeys <command> -h" for the flags of a command.
+`
+
+func newContext() context.Context {
+	ctx := context.Background()
+	ctx = shared.WithLogger(ctx)
+	return ctx
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := newContext()
+	var err error
+	switch os.Args[1] {
+	case "report":
+		err = runReport(ctx, os.Stdout, os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fourkeys %s: %s\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/sink"
+)
+
+type reportOptions struct {
+	from, to    time.Time
+	lookback    time.Duration
+	granularity metrics.Granularity
+	byRepo      bool
+	repo        string
+	format      string
+	thresholds  metrics.Thresholds
+}
+
+func runReport(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	sinkType := fs.String("sink", os.Getenv("SINK_TYPE"), "sink type: bigquery, bigquery_storage, postgres, sqlite or jsonl (env SINK_TYPE)")
+	dsn := fs.String("dsn", os.Getenv("SINK_DSN"), "postgres connection string, or sqlite/jsonl file path (env SINK_DSN)")
+	projectID := fs.String("project", os.Getenv("PROJECT_ID"), "BigQuery project (env PROJECT_ID)")
+	dataset := fs.String("dataset", envOr("BIGQUERY_DATASET", sink.DefaultDataset), "BigQuery dataset (env BIGQUERY_DATASET)")
+	table := fs.String("table", sink.DefaultTable, "events table")
+	from := fs.String("from", "", "start of the range, YYYY-MM-DD or RFC3339 (default 28 days before -to)")
+	to := fs.String("to", "", "end of the range, exclusive, YYYY-MM-DD or RFC3339 (default tomorrow)")
+	lookback := fs.Duration("lookback", 30*24*time.Hour, "also read events this long before -from, so commits deployed in the range are found")
+	group := fs.String("group", "", "split the range: daily, weekly or monthly")
+	byRepo := fs.Bool("by-repo", false, "report each repository separately")
+	repo := fs.String("repo", "", "only report this repository (owner/name)")
+	format := fs.String("format", formatMarkdown, "output format: json, csv or markdown")
+	thresholds := fs.String("thresholds", "", "JSON file overriding the DORA tier thresholds")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	opts := reportOptions{lookback: *lookback, byRepo: *byRepo, repo: *repo, format: *format, thresholds: metrics.DefaultThresholds()}
+	var err error
+	if *thresholds != "" {
+		if opts.thresholds, err = metrics.LoadThresholds(*thresholds); err != nil {
+			return err
+		}
+	}
+	if opts.to, err = parseDate(*to, time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)); err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+	if opts.from, err = parseDate(*from, opts.to.AddDate(0, 0, -28)); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	if !opts.from.Before(opts.to) {
+		return fmt.Errorf("-from %s is not before -to %s", opts.from.Format(time.RFC3339), opts.to.Format(time.RFC3339))
+	}
+	if opts.granularity, err = metrics.ParseGranularity(*group); err != nil {
+		return err
+	}
+	if !validFormat(opts.format) {
+		return fmt.Errorf("unknown format %q", opts.format)
+	}
+
+	r, err := sink.OpenReader(ctx, sink.Config{
+		Type:      *sinkType,
+		DSN:       *dsn,
+		ProjectID: *projectID,
+		Dataset:   *dataset,
+		Table:     *table,
+	})
+	if err != nil {
+		return fmt.Errorf("error opening sink: %w", err)
+	}
+	defer r.Close()
+
+	events, err := r.Read(ctx, opts.from.Add(-opts.lookback), opts.to)
+	if err != nil {
+		return fmt.Errorf("error reading events: %w", err)
+	}
+
+	reportOpts := metrics.ReportOptions{
+		From:         opts.from,
+		To:           opts.to,
+		Granularity:  opts.granularity,
+		ByRepository: opts.byRepo,
+		Thresholds:   opts.thresholds,
+	}
+	if opts.repo != "" {
+		reportOpts.Repositories = []string{opts.repo}
+	}
+	rows := metrics.FromEvents(events).Report(reportOpts)
+	return writeReport(w, opts.format, rows, opts.byRepo || opts.repo != "")
+}
+
+func parseDate(s string, defaultValue time.Time) (time.Time, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func envOr(k, defaultValue string) string {
+	if v, ok := os.LookupEnv(k); ok {
+		return v
+	}
+	return defaultValue
+}
@@ -12,7 +12,7 @@ trap cleanup EXIT
 
 TIMESTAMP=$(TZ=JST-9 date "+%Y%m%d-%H%M%S")
 echo "$TIMESTAMP"
-IMAGE_ID=sisisin/fourkeys-go-github-parser:$TIMESTAMP
+IMAGE_ID=sisisin/fourkeys-go-metrics-api:$TIMESTAMP
 echo "$IMAGE_ID"
 
 # prepare go mod
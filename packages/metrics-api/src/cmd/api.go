@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/httpmw"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/sink"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+	defaultRange    = 28 * 24 * time.Hour
+)
+
+type api struct {
+	reader     sink.Reader
+	thresholds metrics.Thresholds
+	// lookback is read before from, so that commits deployed in the range are found.
+	lookback time.Duration
+	// cache is optional, without it every request reads its range.
+	cache *datasetCache
+	http  httpmw.Config
+}
+
+func (a *api) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/v1/metrics", a.wrap(readOnly(a.getMetrics)))
+	mux.Handle("/v1/deployments", a.wrap(readOnly(a.listDeployments)))
+	mux.Handle("/v1/changes", a.wrap(readOnly(a.listChanges)))
+	mux.Handle("/v1/incidents", a.wrap(readOnly(a.listIncidents)))
+	mux.Handle("/v1/lead_times", a.wrap(readOnly(a.getLeadTimes)))
+	mux.Handle("/v1/repositories", a.wrap(readOnly(a.listRepositories)))
+	mux.Handle("/", a.wrap(readOnly(dashboard())))
+	return mux
+}
+
+func (a *api) wrap(h http.HandlerFunc) http.Handler {
+	return httpmw.Wrap(h, a.http)
+}
+
+func readOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+type rangeQuery struct {
+	from, to time.Time
+	repo     string
+}
+
+func parseRangeQuery(q url.Values, now time.Time) (rangeQuery, error) {
+	var rq rangeQuery
+	var err error
+	if rq.to, err = parseTime(q.Get("to"), now); err != nil {
+		return rq, fmt.Errorf("invalid to: %w", err)
+	}
+	if rq.from, err = parseTime(q.Get("from"), rq.to.Add(-defaultRange)); err != nil {
+		return rq, fmt.Errorf("invalid from: %w", err)
+	}
+	if !rq.from.Before(rq.to) {
+		return rq, fmt.Errorf("from must be before to")
+	}
+	rq.repo = q.Get("repo")
+	return rq, nil
+}
+
+func parseTime(s string, defaultValue time.Time) (time.Time, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func (a *api) dataset(ctx context.Context, rq rangeQuery) (*metrics.Dataset, error) {
+	from := rq.from.Add(-a.lookback)
+	load := func(ctx context.Context) (*metrics.Dataset, error) {
+		events, err := a.reader.Read(ctx, from, rq.to)
+		if err != nil {
+			return nil, fmt.Errorf("error reading events: %w", err)
+		}
+		return metrics.FromEvents(events), nil
+	}
+
+	var d *metrics.Dataset
+	var err error
+	if a.cache != nil {
+		d, err = a.cache.get(ctx, from, rq.to, load)
+	} else {
+		d, err = load(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rq.repo != "" {
+		d = d.ForRepository(rq.repo)
+	}
+	return d, nil
+}
+
+type metricsResponse struct {
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	Repository  string              `json:"repository,omitempty"`
+	Granularity metrics.Granularity `json:"granularity,omitempty"`
+	Periods     []metrics.Row       `json:"periods"`
+}
+
+// getMetrics serves GET /v1/metrics?from=&to=&repo=&granularity=
+func (a *api) getMetrics(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	rq, err := parseRangeQuery(q, today())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	g, err := metrics.ParseGranularity(q.Get("granularity"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	d, err := a.dataset(r.Context(), rq)
+	if err != nil {
+		a.internalError(w, r, err)
+		return
+	}
+	rows := d.Report(metrics.ReportOptions{
+		From:        rq.from,
+		To:          rq.to,
+		Granularity: g,
+		Thresholds:  a.thresholds,
+	})
+	for i := range rows {
+		rows[i].Repository = rq.repo
+	}
+	writeJSON(w, http.StatusOK, metricsResponse{
+		From:        rq.from,
+		To:          rq.to,
+		Repository:  rq.repo,
+		Granularity: g,
+		Periods:     rows,
+	})
+}
+
+type bucket struct {
+	// UpperBoundSeconds is null for the last, unbounded bucket.
+	UpperBoundSeconds *float64 `json:"le_seconds"`
+	Count             int      `json:"count"`
+}
+
+type leadTimesResponse struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Repository string    `json:"repository,omitempty"`
+	Samples    int       `json:"samples"`
+	Buckets    []bucket  `json:"buckets"`
+}
+
+// getLeadTimes serves the lead time distribution of changes deployed in [from, to).
+func (a *api) getLeadTimes(w http.ResponseWriter, r *http.Request) {
+	rq, err := parseRangeQuery(r.URL.Query(), today())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	d, err := a.dataset(r.Context(), rq)
+	if err != nil {
+		a.internalError(w, r, err)
+		return
+	}
+
+	leadTimes := d.LeadTimes(rq.from, rq.to)
+	res := leadTimesResponse{From: rq.from, To: rq.to, Repository: rq.repo, Samples: len(leadTimes)}
+	for _, b := range metrics.Histogram(leadTimes, metrics.DefaultLeadTimeBuckets) {
+		var le *float64
+		if b.UpperBound != metrics.Inf {
+			seconds := b.UpperBound.Seconds()
+			le = &seconds
+		}
+		res.Buckets = append(res.Buckets, bucket{UpperBoundSeconds: le, Count: b.Count})
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// listRepositories serves the repositories with events in [from, to).
+func (a *api) listRepositories(w http.ResponseWriter, r *http.Request) {
+	rq, err := parseRangeQuery(r.URL.Query(), today())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// NOTE: the repo filter does not apply here
+	rq.repo = ""
+	d, err := a.dataset(r.Context(), rq)
+	if err != nil {
+		a.internalError(w, r, err)
+		return
+	}
+	repositories := []string{}
+	for _, repo := range d.Repositories() {
+		if repo != "" {
+			repositories = append(repositories, repo)
+		}
+	}
+	writeJSON(w, http.StatusOK, listResponse[string]{Items: repositories})
+}
+
+type listResponse[T any] struct {
+	Items         []T    `json:"items"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+func (a *api) listDeployments(w http.ResponseWriter, r *http.Request) {
+	list(a, w, r, func(d *metrics.Dataset) []metrics.Deployment { return d.Deployments },
+		func(d metrics.Deployment) time.Time { return d.TimeCreated })
+}
+
+func (a *api) listChanges(w http.ResponseWriter, r *http.Request) {
+	list(a, w, r, func(d *metrics.Dataset) []metrics.Change { return d.Changes },
+		func(c metrics.Change) time.Time { return c.TimeCreated })
+}
+
+func (a *api) listIncidents(w http.ResponseWriter, r *http.Request) {
+	list(a, w, r, func(d *metrics.Dataset) []metrics.Incident { return d.Incidents },
+		func(i metrics.Incident) time.Time { return i.TimeCreated })
+}
+
+// list serves the items created in [from, to), newest first, page by page.
+func list[T any](a *api, w http.ResponseWriter, r *http.Request, items func(*metrics.Dataset) []T, timeCreated func(T) time.Time) {
+	q := r.URL.Query()
+	rq, err := parseRangeQuery(q, today())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	pageSize, offset, err := parsePage(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	d, err := a.dataset(r.Context(), rq)
+	if err != nil {
+		a.internalError(w, r, err)
+		return
+	}
+
+	var selected []T
+	for _, item := range items(d) {
+		if t := timeCreated(item); !t.Before(rq.from) && t.Before(rq.to) {
+			selected = append(selected, item)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return timeCreated(selected[i]).After(timeCreated(selected[j]))
+	})
+
+	res := listResponse[T]{Items: []T{}}
+	if offset < len(selected) {
+		end := offset + pageSize
+		if end < len(selected) {
+			res.NextPageToken = encodePageToken(end)
+		} else {
+			end = len(selected)
+		}
+		res.Items = selected[offset:end]
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func parsePage(q url.Values) (pageSize, offset int, err error) {
+	pageSize = defaultPageSize
+	if v := q.Get("page_size"); v != "" {
+		pageSize, err = strconv.Atoi(v)
+		if err != nil || pageSize < 1 || pageSize > maxPageSize {
+			return 0, 0, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+	}
+	if v := q.Get("page_token"); v != "" {
+		offset, err = decodePageToken(v)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return pageSize, offset, nil
+}
+
+// NOTE: page tokens are offsets, so pages may shift while new events arrive
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errors.New("invalid page_token")
+	}
+	v, ok := strings.CutPrefix(string(b), "offset:")
+	if !ok {
+		return 0, errors.New("invalid page_token")
+	}
+	offset, err := strconv.Atoi(v)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid page_token")
+	}
+	return offset, nil
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+}
+
+func (a *api) internalError(w http.ResponseWriter, r *http.Request, err error) {
+	logger := shared.LoggerFromContext(r.Context())
+	logger.Error(err.Error())
+	writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
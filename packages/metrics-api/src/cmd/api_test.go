@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type stubReader struct {
+	events []*shared.EventRecord
+}
+
+func (r *stubReader) Read(ctx context.Context, from, to time.Time) ([]*shared.EventRecord, error) {
+	var events []*shared.EventRecord
+	for _, e := range r.events {
+		if !e.TimeCreated.Before(from) && e.TimeCreated.Before(to) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *stubReader) Close() error {
+	return nil
+}
+
+func testAPI() *api {
+	events := []*shared.EventRecord{
+		{Source: "github", EventType: "push", Id: "c1", Repository: "org/app", TimeCreated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Metadata: `{
+			"commits": [{"id": "c1", "timestamp": "2024-01-01T00:00:00Z"}]}`},
+		{Source: "github", EventType: "push", Id: "c2", Repository: "org/app", TimeCreated: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Metadata: `{
+			"commits": [{"id": "c2", "timestamp": "2024-01-02T00:00:00Z"}]}`},
+		{Source: "github", EventType: "push", Id: "c3", Repository: "org/other", TimeCreated: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Metadata: `{
+			"commits": [{"id": "c3", "timestamp": "2024-01-03T00:00:00Z"}]}`},
+		{Source: "github", EventType: "deployment_status", Id: "d1", Repository: "org/app", TimeCreated: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), CommitSha: "c2", Metadata: `{
+			"deployment_status": {"state": "success"}}`},
+	}
+	return &api{
+		reader:     &stubReader{events: events},
+		thresholds: metrics.DefaultThresholds(),
+		lookback:   24 * time.Hour,
+	}
+}
+
+type countingReader struct {
+	stubReader
+	reads int
+}
+
+func (r *countingReader) Read(ctx context.Context, from, to time.Time) ([]*shared.EventRecord, error) {
+	r.reads++
+	return r.stubReader.Read(ctx, from, to)
+}
+
+func TestDatasetIsCachedAcrossEndpoints(t *testing.T) {
+	a := testAPI()
+	reader := &countingReader{stubReader: *a.reader.(*stubReader)}
+	a.reader = reader
+	a.cache = newDatasetCache(time.Minute, time.Minute)
+
+	var app, other listResponse[metrics.Change]
+	get(t, a, "/v1/changes?from=2024-01-01&to=2024-01-05&repo=org/app", &app)
+	get(t, a, "/v1/changes?from=2024-01-01&to=2024-01-05&repo=org/other", &other)
+	var res metricsResponse
+	get(t, a, "/v1/metrics?from=2024-01-01&to=2024-01-05", &res)
+
+	if reader.reads != 1 {
+		t.Errorf("reads: %d", reader.reads)
+	}
+	if len(app.Items) != 2 || len(other.Items) != 1 {
+		t.Errorf("changes: %+v, %+v", app.Items, other.Items)
+	}
+}
+
+func get(t *testing.T, a *api, target string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	a.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("error decoding %s: %v", rec.Body.String(), err)
+	}
+	return rec.Code
+}
+
+func TestGetMetrics(t *testing.T) {
+	var res metricsResponse
+	status := get(t, testAPI(), "/v1/metrics?from=2024-01-01&to=2024-01-05&repo=org/app&granularity=daily", &res)
+	if status != http.StatusOK {
+		t.Fatalf("status: %d", status)
+	}
+	if len(res.Periods) != 4 {
+		t.Fatalf("periods: %+v", res.Periods)
+	}
+	if p := res.Periods[1]; p.Deployments != 1 || p.Repository != "org/app" {
+		t.Errorf("2024-01-02: %+v", p)
+	}
+
+	var e map[string]string
+	if status := get(t, testAPI(), "/v1/metrics?granularity=hourly", &e); status != http.StatusBadRequest || e["error"] == "" {
+		t.Errorf("invalid granularity: %d %v", status, e)
+	}
+}
+
+func TestListChanges(t *testing.T) {
+	a := testAPI()
+	var page listResponse[metrics.Change]
+	if status := get(t, a, "/v1/changes?from=2024-01-01&to=2024-01-05&page_size=2", &page); status != http.StatusOK {
+		t.Fatalf("status: %d", status)
+	}
+	if len(page.Items) != 2 || page.Items[0].ChangeID != "c3" || page.NextPageToken == "" {
+		t.Fatalf("first page: %+v", page)
+	}
+
+	var next listResponse[metrics.Change]
+	get(t, a, "/v1/changes?from=2024-01-01&to=2024-01-05&page_size=2&page_token="+page.NextPageToken, &next)
+	if len(next.Items) != 1 || next.Items[0].ChangeID != "c1" || next.NextPageToken != "" {
+		t.Errorf("second page: %+v", next)
+	}
+
+	var repo listResponse[metrics.Change]
+	get(t, a, "/v1/changes?from=2024-01-01&to=2024-01-05&repo=org/other", &repo)
+	if len(repo.Items) != 1 || repo.Items[0].ChangeID != "c3" {
+		t.Errorf("org/other: %+v", repo)
+	}
+
+	var e map[string]string
+	if status := get(t, a, "/v1/changes?page_token=xxx", &e); status != http.StatusBadRequest {
+		t.Errorf("invalid page token: %d", status)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testAPI().routes().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/deployments", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status: %d", rec.Code)
+	}
+}
+
+func TestGetLeadTimes(t *testing.T) {
+	var res leadTimesResponse
+	if status := get(t, testAPI(), "/v1/lead_times?from=2024-01-01&to=2024-01-05", &res); status != http.StatusOK {
+		t.Fatalf("status: %d", status)
+	}
+	// c2 was deployed 12h after its commit
+	if res.Samples != 1 || len(res.Buckets) != len(metrics.DefaultLeadTimeBuckets)+1 || res.Buckets[1].Count != 1 {
+		t.Errorf("lead times: %+v", res)
+	}
+	if res.Buckets[len(res.Buckets)-1].UpperBoundSeconds != nil {
+		t.Errorf("last bucket must be unbounded")
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testAPI().routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "app.js") {
+		t.Errorf("status: %d, body: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxCachedDatasets bounds the memory of the cache, the dashboard asks for a few ranges only.
+const maxCachedDatasets = 32
+
+// datasetCache keeps the datasets of recently requested ranges for ttl.
+// The dashboard requests the same range from several endpoints at once,
+// concurrent misses of a range share a single read.
+type datasetCache struct {
+	ttl time.Duration
+	// timeout bounds a shared read, it does not depend on the caller that started it.
+	timeout time.Duration
+	now     func() time.Time
+
+	group   singleflight.Group
+	mu      sync.Mutex
+	entries map[string]cachedDataset
+}
+
+type cachedDataset struct {
+	dataset *metrics.Dataset
+	expires time.Time
+}
+
+func newDatasetCache(ttl, timeout time.Duration) *datasetCache {
+	return &datasetCache{
+		ttl:     ttl,
+		timeout: timeout,
+		now:     time.Now,
+		entries: map[string]cachedDataset{},
+	}
+}
+
+// get returns the dataset of [from, to), calling load when it is not cached.
+// The dataset is shared between requests and must not be modified.
+func (c *datasetCache) get(ctx context.Context, from, to time.Time, load func(context.Context) (*metrics.Dataset, error)) (*metrics.Dataset, error) {
+	key := from.UTC().Format(time.RFC3339Nano) + "/" + to.UTC().Format(time.RFC3339Nano)
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		return e.dataset, nil
+	}
+
+	// NOTE: the read is shared, a caller that goes away must not cancel it for the others
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		loadContext, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+		defer cancel()
+		d, err := load(loadContext)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, d)
+		return d, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.(*metrics.Dataset), nil
+	}
+}
+
+func (c *datasetCache) put(key string, d *metrics.Dataset) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var oldest string
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		} else if oldest == "" || e.expires.Before(c.entries[oldest].expires) {
+			oldest = k
+		}
+	}
+	if len(c.entries) >= maxCachedDatasets {
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = cachedDataset{dataset: d, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+)
+
+func TestDatasetCache(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	c := newDatasetCache(time.Minute, time.Minute)
+	c.now = func() time.Time { return now }
+
+	loads := 0
+	load := func(context.Context) (*metrics.Dataset, error) {
+		loads++
+		return &metrics.Dataset{}, nil
+	}
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	get := func(from, to time.Time) {
+		t.Helper()
+		if _, err := c.get(context.Background(), from, to, load); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+
+	get(from, to)
+	get(from, to)
+	if loads != 1 {
+		t.Errorf("cached range loads: %d", loads)
+	}
+
+	get(from, to.AddDate(0, 0, 1))
+	if loads != 2 {
+		t.Errorf("other range loads: %d", loads)
+	}
+
+	now = now.Add(time.Minute)
+	get(from, to)
+	if loads != 3 {
+		t.Errorf("expired range loads: %d", loads)
+	}
+}
+
+func TestDatasetCacheError(t *testing.T) {
+	c := newDatasetCache(time.Minute, time.Minute)
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	if _, err := c.get(context.Background(), from, to, func(context.Context) (*metrics.Dataset, error) {
+		return nil, errors.New("unavailable")
+	}); err == nil {
+		t.Fatalf("expected error")
+	}
+	// NOTE: errors are not cached
+	if _, err := c.get(context.Background(), from, to, func(context.Context) (*metrics.Dataset, error) {
+		return &metrics.Dataset{}, nil
+	}); err != nil {
+		t.Errorf("error: %v", err)
+	}
+}
+
+func TestDatasetCacheBounded(t *testing.T) {
+	c := newDatasetCache(time.Minute, time.Minute)
+	load := func(context.Context) (*metrics.Dataset, error) { return &metrics.Dataset{}, nil }
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxCachedDatasets+5; i++ {
+		if _, err := c.get(context.Background(), from, from.Add(time.Duration(i+1)*time.Hour), load); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+	if len(c.entries) != maxCachedDatasets {
+		t.Errorf("entries: %d", len(c.entries))
+	}
+}
+
+func TestDatasetCacheCancelledCaller(t *testing.T) {
+	c := newDatasetCache(time.Minute, time.Minute)
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	load := func(ctx context.Context) (*metrics.Dataset, error) {
+		close(started)
+		<-release
+		return &metrics.Dataset{}, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.get(ctx, from, to, load)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := c.get(context.Background(), from, to, load)
+		second <- err
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller: %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("other caller: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+"use strict";
+
+const form = document.getElementById("filters");
+const errorBox = document.getElementById("error");
+
+function isoDate(d) {
+  return d.toISOString().slice(0, 10);
+}
+
+function query(params) {
+  const q = new URLSearchParams();
+  for (const [k, v] of Object.entries(params)) {
+    if (v) q.set(k, v);
+  }
+  return q.toString();
+}
+
+async function fetchJSON(path, params) {
+  const res = await fetch(`${path}?${query(params)}`);
+  const body = await res.json();
+  if (!res.ok) {
+    throw new Error(body.error || res.statusText);
+  }
+  return body;
+}
+
+function formatDuration(seconds) {
+  if (!seconds) return "0";
+  const units = [["d", 86400], ["h", 3600], ["m", 60]];
+  for (const [unit, size] of units) {
+    if (seconds >= size) return `${(seconds / size).toFixed(1)}${unit}`;
+  }
+  return `${seconds}s`;
+}
+
+function formatPercent(rate) {
+  return `${(rate * 100).toFixed(0)}%`;
+}
+
+function formatPeriod(row) {
+  return row.period_start.slice(5, 10);
+}
+
+// barChart draws labelled bars into an svg element sized by its CSS.
+function barChart(svg, labels, values, format) {
+  const ns = "http://www.w3.org/2000/svg";
+  const width = svg.clientWidth || 300;
+  const height = svg.clientHeight || 200;
+  const padding = { top: 16, bottom: 20 };
+  const max = Math.max(...values, 0) || 1;
+  const step = width / Math.max(values.length, 1);
+  const barWidth = Math.max(step * 0.7, 1);
+
+  svg.replaceChildren();
+  svg.setAttribute("viewBox", `0 0 ${width} ${height}`);
+  values.forEach((v, i) => {
+    const h = ((height - padding.top - padding.bottom) * v) / max;
+    const x = i * step + (step - barWidth) / 2;
+    const y = height - padding.bottom - h;
+
+    const rect = document.createElementNS(ns, "rect");
+    rect.setAttribute("x", x);
+    rect.setAttribute("y", y);
+    rect.setAttribute("width", barWidth);
+    rect.setAttribute("height", h);
+    const title = document.createElementNS(ns, "title");
+    title.textContent = `${labels[i]}: ${format(v)}`;
+    rect.append(title);
+    svg.append(rect);
+
+    const value = document.createElementNS(ns, "text");
+    value.setAttribute("x", x + barWidth / 2);
+    value.setAttribute("y", y - 4);
+    value.setAttribute("text-anchor", "middle");
+    value.textContent = v ? format(v) : "";
+    svg.append(value);
+
+    const label = document.createElementNS(ns, "text");
+    label.setAttribute("x", x + barWidth / 2);
+    label.setAttribute("y", height - 6);
+    label.setAttribute("text-anchor", "middle");
+    label.textContent = labels[i];
+    svg.append(label);
+  });
+}
+
+function renderTiles(row) {
+  const values = {
+    deployment_frequency: `${row.deployments_per_day.toFixed(2)}/day`,
+    lead_time: formatDuration(row.lead_time_median_seconds),
+    change_failure_rate: formatPercent(row.change_failure_rate),
+    time_to_restore: formatDuration(row.time_to_restore_median_seconds),
+  };
+  for (const tile of document.querySelectorAll(".tile")) {
+    const metric = tile.dataset.metric;
+    const tier = row.classification[metric];
+    tile.querySelector(".value").textContent = values[metric];
+    const el = tile.querySelector(".tier");
+    el.textContent = tier.tier;
+    el.title = tier.reason;
+    el.className = `tier tier-${tier.tier}`;
+  }
+}
+
+function renderPeriods(rows) {
+  const labels = rows.map(formatPeriod);
+  barChart(document.getElementById("chart-deployments"), labels,
+    rows.map((r) => r.deployments), String);
+  barChart(document.getElementById("chart-change-failure-rate"), labels,
+    rows.map((r) => r.change_failure_rate), formatPercent);
+  barChart(document.getElementById("chart-time-to-restore"), labels,
+    rows.map((r) => r.time_to_restore_median_seconds), formatDuration);
+}
+
+function renderLeadTimes(res) {
+  const labels = res.buckets.map((b) => (b.le_seconds === null ? "more" : `≤${formatDuration(b.le_seconds)}`));
+  barChart(document.getElementById("chart-lead-times"), labels,
+    res.buckets.map((b) => b.count), String);
+}
+
+async function loadRepositories(params) {
+  const select = form.elements.repo;
+  const selected = select.value;
+  const res = await fetchJSON("v1/repositories", { from: params.from, to: params.to });
+  select.replaceChildren(new Option("All repositories", ""));
+  for (const repo of res.items) {
+    select.append(new Option(repo, repo, false, repo === selected));
+  }
+}
+
+async function refresh() {
+  const params = {
+    from: form.elements.from.value,
+    to: form.elements.to.value,
+    repo: form.elements.repo.value,
+  };
+  history.replaceState(null, "", `?${query({ ...params, granularity: form.elements.granularity.value })}`);
+  errorBox.hidden = true;
+  try {
+    const [total, periods, leadTimes] = await Promise.all([
+      fetchJSON("v1/metrics", params),
+      fetchJSON("v1/metrics", { ...params, granularity: form.elements.granularity.value }),
+      fetchJSON("v1/lead_times", params),
+      loadRepositories(params),
+    ]);
+    renderTiles(total.periods[0]);
+    renderPeriods(periods.periods);
+    renderLeadTimes(leadTimes);
+  } catch (e) {
+    errorBox.textContent = e.message;
+    errorBox.hidden = false;
+  }
+}
+
+function init() {
+  const q = new URLSearchParams(location.search);
+  const to = new Date();
+  to.setUTCDate(to.getUTCDate() + 1);
+  const from = new Date(to);
+  from.setUTCDate(from.getUTCDate() - 28);
+
+  form.elements.from.value = q.get("from") || isoDate(from);
+  form.elements.to.value = q.get("to") || isoDate(to);
+  form.elements.granularity.value = q.get("granularity") || "weekly";
+  if (q.get("repo")) {
+    form.elements.repo.append(new Option(q.get("repo"), q.get("repo"), true, true));
+  }
+
+  form.addEventListener("submit", (e) => {
+    e.preventDefault();
+    refresh();
+  });
+  form.elements.repo.addEventListener("change", refresh);
+  refresh();
+}
+
+init();
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/sink"
+)
+
+const (
+	// otherRepository collects the repositories beyond the cardinality limit.
+	otherRepository = "other"
+	// unknownRepository labels events without a repository, e.g. PagerDuty incidents.
+	unknownRepository = "unknown"
+)
+
+var (
+	deploymentsDesc = prometheus.NewDesc("fourkeys_deployments",
+		"Number of deployments in the window.", []string{"repository"}, nil)
+	deploymentsPerDayDesc = prometheus.NewDesc("fourkeys_deployments_per_day",
+		"Deployments per day over the window.", []string{"repository"}, nil)
+	changesDesc = prometheus.NewDesc("fourkeys_changes",
+		"Number of changes in the window.", []string{"repository"}, nil)
+	leadTimeDesc = prometheus.NewDesc("fourkeys_lead_time_seconds",
+		"Time from commit to deployment of changes deployed in the window.", []string{"repository"}, nil)
+	failedDeploymentsDesc = prometheus.NewDesc("fourkeys_failed_deployments",
+		"Number of deployments in the window that caused an incident.", []string{"repository"}, nil)
+	changeFailureRateDesc = prometheus.NewDesc("fourkeys_change_failure_rate",
+		"Ratio of failed deployments to deployments in the window.", []string{"repository"}, nil)
+	incidentsDesc = prometheus.NewDesc("fourkeys_incidents",
+		"Number of incidents in the window.", []string{"repository"}, nil)
+	timeToRestoreDesc = prometheus.NewDesc("fourkeys_time_to_restore_seconds",
+		"Time to restore of incidents resolved in the window.", []string{"repository"}, nil)
+	windowDesc = prometheus.NewDesc("fourkeys_window_seconds",
+		"Length of the window the metrics are computed over.", nil, nil)
+	droppedRepositoriesDesc = prometheus.NewDesc("fourkeys_exporter_dropped_repositories",
+		"Number of repositories reported as \""+otherRepository+"\" because of the cardinality limit.", nil, nil)
+	lastRefreshDesc = prometheus.NewDesc("fourkeys_exporter_last_refresh_timestamp_seconds",
+		"Time of the last successful refresh.", nil, nil)
+)
+
+type exporterConfig struct {
+	// window is the length of the trailing window the metrics are computed over.
+	window time.Duration
+	// lookback is read before the window, like the API does.
+	lookback        time.Duration
+	maxRepositories int
+}
+
+// exporter exposes the four keys of a trailing window as Prometheus metrics.
+// The sink is read on refresh only, so scrapes never hit it.
+type exporter struct {
+	reader sink.Reader
+	config exporterConfig
+
+	mu       sync.RWMutex
+	snapshot []prometheus.Metric
+
+	refreshErrors prometheus.Counter
+}
+
+func newExporter(r sink.Reader, c exporterConfig) *exporter {
+	return &exporter{
+		reader: r,
+		config: c,
+		refreshErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "fourkeys_exporter_refresh_errors_total",
+			Help: "Number of failed refreshes.",
+		}),
+	}
+}
+
+func (e *exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		deploymentsDesc, deploymentsPerDayDesc, changesDesc, leadTimeDesc,
+		failedDeploymentsDesc, changeFailureRateDesc, incidentsDesc, timeToRestoreDesc,
+		windowDesc, droppedRepositoriesDesc, lastRefreshDesc,
+	} {
+		ch <- d
+	}
+	e.refreshErrors.Describe(ch)
+}
+
+func (e *exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, m := range e.snapshot {
+		ch <- m
+	}
+	e.refreshErrors.Collect(ch)
+}
+
+// run refreshes the snapshot every interval until ctx is done.
+func (e *exporter) run(ctx context.Context, interval time.Duration) {
+	logger := shared.LoggerFromContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := e.refresh(ctx, time.Now()); err != nil {
+			e.refreshErrors.Inc()
+			logger.Error(fmt.Sprintf("error refreshing exporter: %s", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *exporter) refresh(ctx context.Context, now time.Time) error {
+	to := now.UTC()
+	from := to.Add(-e.config.window)
+	events, err := e.reader.Read(ctx, from.Add(-e.config.lookback), to)
+	if err != nil {
+		return fmt.Errorf("error reading events: %w", err)
+	}
+
+	datasets, dropped := limitRepositories(metrics.FromEvents(events), e.config.maxRepositories)
+	snapshot := []prometheus.Metric{
+		prometheus.MustNewConstMetric(windowDesc, prometheus.GaugeValue, e.config.window.Seconds()),
+		prometheus.MustNewConstMetric(droppedRepositoriesDesc, prometheus.GaugeValue, float64(dropped)),
+		prometheus.MustNewConstMetric(lastRefreshDesc, prometheus.GaugeValue, float64(now.Unix())),
+	}
+	for repo, d := range datasets {
+		s := d.Summarize(from, to)
+		snapshot = append(snapshot,
+			prometheus.MustNewConstMetric(deploymentsDesc, prometheus.GaugeValue, float64(s.Deployments), repo),
+			prometheus.MustNewConstMetric(deploymentsPerDayDesc, prometheus.GaugeValue, s.DeploymentsPerDay, repo),
+			prometheus.MustNewConstMetric(changesDesc, prometheus.GaugeValue, float64(s.Changes), repo),
+			prometheus.MustNewConstMetric(failedDeploymentsDesc, prometheus.GaugeValue, float64(s.FailedDeployments), repo),
+			prometheus.MustNewConstMetric(changeFailureRateDesc, prometheus.GaugeValue, s.ChangeFailureRate, repo),
+			prometheus.MustNewConstMetric(incidentsDesc, prometheus.GaugeValue, float64(s.Incidents), repo),
+			constHistogram(leadTimeDesc, d.LeadTimes(from, to), metrics.DefaultLeadTimeBuckets, repo),
+			constHistogram(timeToRestoreDesc, d.RestoreTimes(from, to), metrics.DefaultTimeToRestoreBuckets, repo),
+		)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.snapshot = snapshot
+	return nil
+}
+
+func constHistogram(desc *prometheus.Desc, values []time.Duration, bounds []time.Duration, labelValues ...string) prometheus.Metric {
+	var sum float64
+	for _, v := range values {
+		sum += v.Seconds()
+	}
+	buckets := map[float64]uint64{}
+	var cumulative uint64
+	for _, b := range metrics.Histogram(values, bounds) {
+		cumulative += uint64(b.Count)
+		if b.UpperBound != metrics.Inf {
+			buckets[b.UpperBound.Seconds()] = cumulative
+		}
+	}
+	return prometheus.MustNewConstHistogram(desc, uint64(len(values)), sum, buckets, labelValues...)
+}
+
+// limitRepositories splits d by repository label. Only the max most active
+// repositories get their own label, the rest are merged into "other".
+func limitRepositories(d *metrics.Dataset, max int) (map[string]*metrics.Dataset, int) {
+	repos := d.Repositories()
+	activity := map[string]int{}
+	for _, c := range d.Changes {
+		activity[c.Repository]++
+	}
+	for _, dep := range d.Deployments {
+		activity[dep.Repository]++
+	}
+	for _, i := range d.Incidents {
+		activity[i.Repository]++
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		return activity[repos[i]] > activity[repos[j]]
+	})
+
+	datasets := map[string]*metrics.Dataset{}
+	dropped := 0
+	for i, repo := range repos {
+		label := repo
+		if label == "" {
+			label = unknownRepository
+		}
+		if max > 0 && i >= max {
+			label = otherRepository
+			dropped++
+		}
+		sub := d.ForRepository(repo)
+		if merged, ok := datasets[label]; ok {
+			merged.Changes = append(merged.Changes, sub.Changes...)
+			merged.Deployments = append(merged.Deployments, sub.Deployments...)
+			merged.Incidents = append(merged.Incidents, sub.Incidents...)
+		} else {
+			datasets[label] = sub
+		}
+	}
+	return datasets, dropped
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+)
+
+func TestExporter(t *testing.T) {
+	a := testAPI()
+	e := newExporter(a.reader, exporterConfig{window: 4 * 24 * time.Hour, lookback: 24 * time.Hour, maxRepositories: 1})
+	if err := e.refresh(context.Background(), time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	want := `
+# HELP fourkeys_deployments Number of deployments in the window.
+# TYPE fourkeys_deployments gauge
+fourkeys_deployments{repository="org/app"} 1
+fourkeys_deployments{repository="other"} 0
+# HELP fourkeys_exporter_dropped_repositories Number of repositories reported as "other" because of the cardinality limit.
+# TYPE fourkeys_exporter_dropped_repositories gauge
+fourkeys_exporter_dropped_repositories 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(want), "fourkeys_deployments", "fourkeys_exporter_dropped_repositories"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(e, "fourkeys_lead_time_seconds"); n != 2 {
+		t.Errorf("lead time histograms: %d", n)
+	}
+}
+
+func TestLimitRepositories(t *testing.T) {
+	d := &metrics.Dataset{
+		Changes: []metrics.Change{
+			{ChangeID: "1", Repository: "org/a"},
+			{ChangeID: "2", Repository: "org/b"},
+			{ChangeID: "3", Repository: "org/b"},
+			{ChangeID: "4", Repository: "org/c"},
+		},
+		Incidents: []metrics.Incident{{IncidentID: "5"}},
+	}
+	datasets, dropped := limitRepositories(d, 2)
+	if dropped != 2 || len(datasets) != 3 {
+		t.Fatalf("dropped %d, datasets %v", dropped, datasets)
+	}
+	if len(datasets["org/b"].Changes) != 2 || len(datasets[otherRepository].Changes)+len(datasets[otherRepository].Incidents) != 2 {
+		t.Errorf("datasets: %+v", datasets)
+	}
+
+	if _, dropped := limitRepositories(d, 0); dropped != 0 {
+		t.Errorf("0 must not limit")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sisisin-sandbox/fourkeys-go/metrics"
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/httpmw"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/sink"
+)
+
+type environmentVariables struct {
+	port           string
+	metricsPort    string
+	projectID      string
+	sinkType       string
+	sinkDSN        string
+	dataset        string
+	thresholdsPath string
+	lookback       time.Duration
+	requestTimeout time.Duration
+	cacheTTL       time.Duration
+
+	exporterInterval        time.Duration
+	exporterWindow          time.Duration
+	exporterMaxRepositories int
+}
+
+var envVars environmentVariables
+
+func init() {
+	envVars.projectID = os.Getenv("PROJECT_ID")
+	envVars.sinkType = os.Getenv("SINK_TYPE")
+	envVars.sinkDSN = os.Getenv("SINK_DSN")
+	envVars.thresholdsPath = os.Getenv("THRESHOLDS_PATH")
+	{
+		dataset, ok := os.LookupEnv("BIGQUERY_DATASET")
+		if ok {
+			envVars.dataset = dataset
+		} else {
+			envVars.dataset = sink.DefaultDataset
+		}
+	}
+	envVars.lookback = durationEnv("LOOKBACK", 30*24*time.Hour)
+	envVars.requestTimeout = durationEnv("REQUEST_TIMEOUT", time.Minute)
+	// NOTE: 0 disables the cache
+	envVars.cacheTTL = durationEnv("CACHE_TTL", time.Minute)
+	envVars.exporterInterval = durationEnv("EXPORTER_INTERVAL", 5*time.Minute)
+	envVars.exporterWindow = durationEnv("EXPORTER_WINDOW", 28*24*time.Hour)
+	{
+		max, ok := os.LookupEnv("EXPORTER_MAX_REPOSITORIES")
+		if ok {
+			n, err := strconv.Atoi(max)
+			if err != nil {
+				panic("EXPORTER_MAX_REPOSITORIES must be an integer: " + err.Error())
+			}
+			envVars.exporterMaxRepositories = n
+		} else {
+			envVars.exporterMaxRepositories = 50
+		}
+	}
+	{
+		port, ok := os.LookupEnv("PORT")
+		if ok {
+			envVars.port = port
+		} else {
+			envVars.port = "8080"
+		}
+	}
+	{
+		// NOTE: /metrics is not served on PORT, which is the public API and dashboard
+		port, ok := os.LookupEnv("METRICS_PORT")
+		if ok {
+			envVars.metricsPort = port
+		} else {
+			envVars.metricsPort = "9090"
+		}
+	}
+}
+
+func durationEnv(key string, defaultValue time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		panic(key + " must be a duration: " + err.Error())
+	}
+	return d
+}
+
+func newContext() context.Context {
+	ctx := context.Background()
+	ctx = shared.WithLogger(ctx)
+	return ctx
+}
+
+func main() {
+	mainContext := newContext()
+	logger := shared.LoggerFromContext(mainContext)
+
+	thresholds := metrics.DefaultThresholds()
+	if envVars.thresholdsPath != "" {
+		t, err := metrics.LoadThresholds(envVars.thresholdsPath)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		thresholds = t
+	}
+
+	r, err := sink.OpenReader(mainContext, sink.Config{
+		Type:      envVars.sinkType,
+		DSN:       envVars.sinkDSN,
+		ProjectID: envVars.projectID,
+		Dataset:   envVars.dataset,
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("error opening sink: %s", err))
+		os.Exit(1)
+	}
+	defer r.Close()
+
+	a := &api{
+		reader:     r,
+		thresholds: thresholds,
+		lookback:   envVars.lookback,
+		http: httpmw.Config{
+			ProjectID: envVars.projectID,
+			// NOTE: the API is read-only, requests carry no body
+			MaxBodyBytes: 1 << 20,
+			Timeout:      envVars.requestTimeout,
+		},
+	}
+	if envVars.cacheTTL > 0 {
+		a.cache = newDatasetCache(envVars.cacheTTL, envVars.requestTimeout)
+	}
+	e := newExporter(r, exporterConfig{
+		window:          envVars.exporterWindow,
+		lookback:        envVars.lookback,
+		maxRepositories: envVars.exporterMaxRepositories,
+	})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+
+	signalContext, stop := signal.NotifyContext(mainContext, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go e.run(signalContext, envVars.exporterInterval)
+	go func() {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		if err := listen(signalContext, envVars.metricsPort, metricsMux); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+	if err := listen(signalContext, envVars.port, a.routes()); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// listen serves handler on port until ctx is done.
+func listen(ctx context.Context, port string, handler http.Handler) error {
+	logger := shared.LoggerFromContext(ctx)
+
+	addr := ":" + port
+	server := &http.Server{Addr: addr, Handler: handler}
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info(fmt.Sprintf("listening on %s", addr))
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("error serving: %w", err)
+	case <-ctx.Done():
+	}
+
+	// NOTE: Cloud Run allows 10 seconds between SIGTERM and SIGKILL
+	shutdownContext, cancel := context.WithTimeout(context.WithoutCancel(ctx), 8*time.Second)
+	defer cancel()
+	logger.Info("shutting down")
+	if err := server.Shutdown(shutdownContext); err != nil {
+		return fmt.Errorf("error shutting down server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,412 @@
+// Package metrics computes the four DORA keys from events_raw records.
+//
+// FromEvents mirrors the changes, deployments and incidents queries under
+// infra/modules/fourkeys/queries, so the results can be checked against BigQuery.
+package metrics
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type Change struct {
+	Source      string    `json:"source"`
+	ChangeID    string    `json:"change_id"`
+	Repository  string    `json:"repository,omitempty"`
+	TimeCreated time.Time `json:"time_created"`
+}
+
+type Deployment struct {
+	Source      string    `json:"source"`
+	DeployID    string    `json:"deploy_id"`
+	Repository  string    `json:"repository,omitempty"`
+	MainCommit  string    `json:"main_commit"`
+	TimeCreated time.Time `json:"time_created"`
+	// Changes are the commit ids shipped by the deployment.
+	Changes []string `json:"changes"`
+}
+
+type Incident struct {
+	Source      string    `json:"source"`
+	IncidentID  string    `json:"incident_id"`
+	Repository  string    `json:"repository,omitempty"`
+	TimeCreated time.Time `json:"time_created"`
+	// TimeResolved is zero while the incident is open.
+	TimeResolved time.Time `json:"time_resolved"`
+	// Changes are the root cause commit ids.
+	Changes []string `json:"changes"`
+}
+
+// MarshalJSON writes an open incident's time_resolved as null.
+func (i Incident) MarshalJSON() ([]byte, error) {
+	type incident Incident
+	v := struct {
+		incident
+		TimeResolved *time.Time `json:"time_resolved"`
+	}{incident: incident(i)}
+	if !i.TimeResolved.IsZero() {
+		v.TimeResolved = &i.TimeResolved
+	}
+	return json.Marshal(v)
+}
+
+type Dataset struct {
+	Changes     []Change
+	Deployments []Deployment
+	Incidents   []Incident
+}
+
+// FromEvents derives changes, deployments and incidents from events.
+// Like the SQL, values that are missing or can not be parsed are treated as NULL
+// instead of failing the whole computation.
+// Events stored more than once are counted once, by msg_id.
+func FromEvents(events []*shared.EventRecord) *Dataset {
+	events = shared.UniqueByMsgId(events)
+	decoded := make([]decodedEvent, 0, len(events))
+	for _, e := range events {
+		metadata, err := shared.DecodeJSON([]byte(e.Metadata))
+		if err != nil {
+			metadata = map[string]interface{}{}
+		}
+		decoded = append(decoded, decodedEvent{EventRecord: e, metadata: metadata})
+	}
+
+	d := &Dataset{
+		Changes:     changes(decoded),
+		Deployments: deployments(decoded, indexById(decoded)),
+	}
+	d.Incidents = incidents(decoded, d.Deployments)
+	return d
+}
+
+type decodedEvent struct {
+	*shared.EventRecord
+	metadata map[string]interface{}
+}
+
+func (e decodedEvent) str(expr string) string {
+	v, err := shared.LookupPath[interface{}](e.metadata, expr)
+	if err != nil {
+		return ""
+	}
+	s, _ := shared.ToString(v)
+	return s
+}
+
+func (e decodedEvent) strings(expr string) []string {
+	v, err := shared.LookupPath[interface{}](e.metadata, expr)
+	if err != nil {
+		return nil
+	}
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+	var result []string
+	for _, v := range values {
+		if s, err := shared.ToString(v); err == nil {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (e decodedEvent) time(expr string) time.Time {
+	s := e.str(expr)
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := shared.ParseTimestamp(s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func isGithub(source string) bool {
+	return strings.HasPrefix(source, "github")
+}
+
+func isGitlab(source string) bool {
+	return strings.HasPrefix(source, "gitlab")
+}
+
+// changes mirrors changes.sql.
+func changes(events []decodedEvent) []Change {
+	type key struct{ source, id string }
+	seen := map[key]bool{}
+	var result []Change
+	for _, e := range events {
+		if e.EventType != "push" {
+			continue
+		}
+		if e.IsDefaultBranch != nil && !*e.IsDefaultBranch {
+			continue
+		}
+		commits, _ := shared.LookupMap[[]interface{}](e.metadata, "commits")
+		for _, c := range commits {
+			commit, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, err := shared.LookupString(commit, "id")
+			if err != nil {
+				continue
+			}
+			k := key{e.Source, id}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			t, _ := shared.LookupTime(commit, "timestamp")
+			result = append(result, Change{
+				Source:      e.Source,
+				ChangeID:    id,
+				Repository:  e.Repository,
+				TimeCreated: t.Truncate(time.Second),
+			})
+		}
+	}
+	return result
+}
+
+var commitURLPattern = regexp.MustCompile(`.*commit/(.*)`)
+
+// deployments mirrors deployments.sql.
+func deployments(events []decodedEvent, byId map[string][]decodedEvent) []Deployment {
+	var deploys []Deployment
+	for _, e := range events {
+		d := Deployment{
+			Source:      e.Source,
+			DeployID:    e.Id,
+			Repository:  e.Repository,
+			TimeCreated: e.TimeCreated,
+		}
+		var additional []string
+		switch {
+		case e.Source == "cloud_build" && e.str("status") == "SUCCESS":
+			d.MainCommit = e.str("substitutions.COMMIT_SHA")
+		case isGithub(e.Source) && e.EventType == "deployment_status" && e.str("deployment_status.state") == "success":
+			d.MainCommit = e.CommitSha
+			if d.MainCommit == "" {
+				d.MainCommit = e.str("deployment.sha")
+			}
+			additional = e.strings("deployment.additional_sha[*]")
+		case isGitlab(e.Source) && e.EventType == "pipeline" && e.str("object_attributes.status") == "success",
+			isGitlab(e.Source) && e.EventType == "deployment" && e.str("status") == "success":
+			d.MainCommit = e.str("commit.id")
+			if d.MainCommit == "" {
+				if m := commitURLPattern.FindStringSubmatch(e.str("commit_url")); m != nil {
+					d.MainCommit = m[1]
+				}
+			}
+		case e.Source == "argocd" && e.str("status") == "SUCCESS":
+			d.MainCommit = e.str("commit_sha")
+		case e.EventType == "dev.tekton.event.pipelinerun.successful.v1":
+			d.TimeCreated = d.TimeCreated.Truncate(time.Second)
+			params, _ := shared.LookupPath[[]interface{}](e.metadata, "data.pipelineRun.spec.params")
+			for _, p := range params {
+				param, ok := p.(map[string]interface{})
+				if !ok || param["name"] != "gitrevision" {
+					continue
+				}
+				d.MainCommit, _ = shared.LookupString(param, "value")
+			}
+		case e.Source == "circleci" && e.EventType == "workflow-completed" &&
+			strings.Contains(e.str("workflow.name"), "deploy") && e.str("workflow.status") == "success":
+			d.MainCommit = e.str("pipeline.vcs.revision")
+		default:
+			continue
+		}
+		if d.MainCommit == "" && len(additional) == 0 {
+			continue
+		}
+		d.Changes = deployedChanges(byId, append([]string{d.MainCommit}, additional...))
+		// NOTE: deployments.sql inner joins on the change events, deployments without known changes are dropped
+		if len(d.Changes) == 0 {
+			continue
+		}
+		deploys = append(deploys, d)
+	}
+	return deploys
+}
+
+// indexById groups events by id, so deployed commits are looked up without scanning every event.
+func indexById(events []decodedEvent) map[string][]decodedEvent {
+	byId := map[string][]decodedEvent{}
+	for _, e := range events {
+		byId[e.Id] = append(byId[e.Id], e)
+	}
+	return byId
+}
+
+// deployedChanges collects the commits of the events whose id is one of the deployed commits.
+func deployedChanges(byId map[string][]decodedEvent, commits []string) []string {
+	set := map[string]bool{}
+	for _, c := range commits {
+		if c == "" {
+			continue
+		}
+		for _, e := range byId[c] {
+			for _, id := range e.strings("commits[*].id") {
+				set[id] = true
+			}
+		}
+	}
+	return sortedKeys(set)
+}
+
+var rootCausePattern = regexp.MustCompile(`root cause: ([[:alnum:]]*)`)
+
+// incidents mirrors incidents.sql, except that incidents are grouped per repository too.
+func incidents(events []decodedEvent, deploys []Deployment) []Incident {
+	deployTimes := map[string]time.Time{}
+	for _, d := range deploys {
+		for _, c := range d.Changes {
+			if t, ok := deployTimes[c]; !ok || d.TimeCreated.Before(t) {
+				deployTimes[c] = d.TimeCreated
+			}
+		}
+	}
+
+	// NOTE: issue numbers are only unique within a repository
+	type key struct{ source, repository, id string }
+	type aggregate struct {
+		Incident
+		bug    bool
+		causes map[string]bool
+	}
+	aggregates := map[key]*aggregate{}
+	var order []key
+
+	for _, e := range events {
+		isIssueNote := e.EventType == "note" && e.str("object_attributes.noteable_type") == "Issue"
+		if !strings.HasPrefix(e.EventType, "issue") && !strings.HasPrefix(e.EventType, "incident") && !isIssueNote {
+			continue
+		}
+
+		var (
+			id                string
+			created, resolved time.Time
+			bug               bool
+		)
+		switch {
+		case isGithub(e.Source):
+			id = e.str("issue.number")
+			created, resolved = e.time("issue.created_at"), e.time("issue.closed_at")
+			bug = contains(e.strings("issue.labels[*].name"), "Incident")
+		case isGitlab(e.Source):
+			if e.EventType == "note" {
+				id = e.str("object_attributes.noteable_id")
+			} else if e.EventType == "issue" {
+				id = e.str("object_attributes.id")
+			}
+			created, resolved = e.time("object_attributes.created_at"), e.time("object_attributes.closed_at")
+			bug = contains(e.strings("object_attributes.labels[*].title"), "Incident")
+		case strings.HasPrefix(e.Source, "pagerduty"):
+			id = e.str("event.data.id")
+			created = e.time("event.occurred_at")
+			resolved = created
+			bug = true
+		}
+
+		k := key{e.Source, e.Repository, id}
+		a, ok := aggregates[k]
+		if !ok {
+			a = &aggregate{
+				Incident: Incident{Source: e.Source, IncidentID: id, Repository: e.Repository},
+				causes:   map[string]bool{},
+			}
+			aggregates[k] = a
+			order = append(order, k)
+		}
+		a.bug = a.bug || bug
+
+		var rootCause string
+		if m := rootCausePattern.FindStringSubmatch(e.Metadata); m != nil {
+			rootCause = m[1]
+			a.causes[rootCause] = true
+		}
+		// NOTE: the incident starts at the earlier of the issue and the deployment of its root cause
+		if t, ok := deployTimes[rootCause]; ok && t.Before(created) {
+			created = t
+		}
+		if !created.IsZero() && (a.TimeCreated.IsZero() || created.Before(a.TimeCreated)) {
+			a.TimeCreated = created
+		}
+		if resolved.After(a.TimeResolved) {
+			a.TimeResolved = resolved
+		}
+	}
+
+	var result []Incident
+	for _, k := range order {
+		a := aggregates[k]
+		if !a.bug {
+			continue
+		}
+		a.Changes = sortedKeys(a.causes)
+		result = append(result, a.Incident)
+	}
+	return result
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Repositories returns the repositories seen in d, sorted. Events without a
+// repository (e.g. PagerDuty incidents) are reported under "".
+func (d *Dataset) Repositories() []string {
+	set := map[string]bool{}
+	for _, c := range d.Changes {
+		set[c.Repository] = true
+	}
+	for _, dep := range d.Deployments {
+		set[dep.Repository] = true
+	}
+	for _, i := range d.Incidents {
+		set[i.Repository] = true
+	}
+	return sortedKeys(set)
+}
+
+// ForRepository returns the subset of d recorded for repository.
+func (d *Dataset) ForRepository(repository string) *Dataset {
+	filtered := &Dataset{}
+	for _, c := range d.Changes {
+		if c.Repository == repository {
+			filtered.Changes = append(filtered.Changes, c)
+		}
+	}
+	for _, dep := range d.Deployments {
+		if dep.Repository == repository {
+			filtered.Deployments = append(filtered.Deployments, dep)
+		}
+	}
+	for _, i := range d.Incidents {
+		if i.Repository == repository {
+			filtered.Incidents = append(filtered.Incidents, i)
+		}
+	}
+	return filtered
+}
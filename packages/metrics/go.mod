@@ -0,0 +1,7 @@
+module github.com/sisisin-sandbox/fourkeys-go/metrics
+
+go 1.21.6
+
+replace github.com/sisisin-sandbox/fourkeys-go/shared => ./../shared
+
+require github.com/sisisin-sandbox/fourkeys-go/shared v0.0.0-00010101000000-000000000000
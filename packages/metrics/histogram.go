@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"math"
+	"time"
+)
+
+// Inf is the upper bound of the last bucket of a histogram.
+const Inf = time.Duration(math.MaxInt64)
+
+// DefaultLeadTimeBuckets follow the lead time bands of the State of DevOps report.
+var DefaultLeadTimeBuckets = []time.Duration{
+	time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	180 * 24 * time.Hour,
+}
+
+// DefaultTimeToRestoreBuckets follow the time to restore bands of the State of DevOps report.
+var DefaultTimeToRestoreBuckets = []time.Duration{
+	time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+}
+
+// Bucket counts the values greater than the previous bucket's UpperBound and
+// less than or equal to its own.
+type Bucket struct {
+	UpperBound time.Duration
+	Count      int
+}
+
+// Histogram sorts values into buckets bounded by the ascending bounds,
+// followed by an Inf bucket for the rest.
+func Histogram(values []time.Duration, bounds []time.Duration) []Bucket {
+	buckets := make([]Bucket, len(bounds)+1)
+	for i, b := range bounds {
+		buckets[i].UpperBound = b
+	}
+	buckets[len(bounds)].UpperBound = Inf
+
+	for _, v := range values {
+		for i := range buckets {
+			if v <= buckets[i].UpperBound {
+				buckets[i].Count++
+				break
+			}
+		}
+	}
+	return buckets
+}
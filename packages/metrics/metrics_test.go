@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func day(d, h int) time.Time {
+	return time.Date(2024, 1, d, h, 0, 0, 0, time.UTC)
+}
+
+func testEvents() []*shared.EventRecord {
+	notDefault := false
+	return []*shared.EventRecord{
+		// changes
+		{Source: "github", EventType: "push", Id: "c2", Repository: "org/app", Metadata: `{
+			"commits": [
+				{"id": "c1", "timestamp": "2024-01-01T00:00:00Z"},
+				{"id": "c2", "timestamp": "2024-01-01T06:00:00Z"}
+			]}`},
+		{Source: "github", EventType: "push", Id: "c3", Metadata: `{
+			"commits": [{"id": "c3", "timestamp": "2024-01-02T00:00:00Z"}]}`},
+		{Source: "github", EventType: "push", Id: "f1", IsDefaultBranch: &notDefault, Metadata: `{
+			"commits": [{"id": "f1", "timestamp": "2024-01-02T00:00:00Z"}]}`},
+
+		// deployments
+		{Source: "github", EventType: "deployment_status", Id: "d1", TimeCreated: day(1, 12), CommitSha: "c2", Metadata: `{
+			"deployment_status": {"state": "success"}}`},
+		{Source: "github", EventType: "deployment_status", Id: "d2", TimeCreated: day(3, 0), Metadata: `{
+			"deployment_status": {"state": "success"}, "deployment": {"sha": "c3"}}`},
+		{Source: "github", EventType: "deployment_status", Id: "d3", TimeCreated: day(3, 1), Metadata: `{
+			"deployment_status": {"state": "failure"}, "deployment": {"sha": "c3"}}`},
+		{Source: "circleci", EventType: "workflow-completed", Id: "d4", TimeCreated: day(3, 2), Metadata: `{
+			"workflow": {"name": "deploy-prod", "status": "success"}, "pipeline": {"vcs": {"revision": "unknown"}}}`},
+
+		// incidents
+		{Source: "github", EventType: "issues", Id: "i1", Metadata: `{
+			"issue": {"number": 10, "created_at": "2024-01-03T06:00:00Z", "closed_at": "2024-01-03T08:00:00Z",
+			"labels": [{"name": "Incident"}], "body": "root cause: c3"}}`},
+		{Source: "github", EventType: "issues", Id: "i2", Metadata: `{
+			"issue": {"number": 11, "created_at": "2024-01-03T06:00:00Z", "closed_at": null,
+			"labels": [{"name": "bug"}]}}`},
+		{Source: "pagerduty", EventType: "incident.triggered", Id: "p1", Metadata: `{
+			"event": {"occurred_at": "2024-01-04T00:00:00Z", "data": {"id": "PD1"}}}`},
+	}
+}
+
+func TestFromEvents(t *testing.T) {
+	d := FromEvents(testEvents())
+
+	var changeIDs []string
+	for _, c := range d.Changes {
+		changeIDs = append(changeIDs, c.ChangeID)
+	}
+	if want := []string{"c1", "c2", "c3"}; !reflect.DeepEqual(changeIDs, want) {
+		t.Errorf("changes: %v, want %v", changeIDs, want)
+	}
+
+	if len(d.Deployments) != 2 {
+		t.Fatalf("deployments: %+v", d.Deployments)
+	}
+	if got := d.Deployments[0].Changes; !reflect.DeepEqual(got, []string{"c1", "c2"}) {
+		t.Errorf("d1 changes: %v", got)
+	}
+	if d.Deployments[1].MainCommit != "c3" {
+		t.Errorf("d2 main commit: %s", d.Deployments[1].MainCommit)
+	}
+
+	if len(d.Incidents) != 2 {
+		t.Fatalf("incidents: %+v", d.Incidents)
+	}
+	gh := d.Incidents[0]
+	if gh.IncidentID != "10" || !reflect.DeepEqual(gh.Changes, []string{"c3"}) {
+		t.Errorf("github incident: %+v", gh)
+	}
+	// NOTE: starts when the root cause was deployed, not when the issue was opened
+	if !gh.TimeCreated.Equal(day(3, 0)) || !gh.TimeResolved.Equal(day(3, 8)) {
+		t.Errorf("github incident times: %v - %v", gh.TimeCreated, gh.TimeResolved)
+	}
+	if d.Incidents[1].IncidentID != "PD1" {
+		t.Errorf("pagerduty incident: %+v", d.Incidents[1])
+	}
+}
+
+func TestFromEventsRedelivered(t *testing.T) {
+	events := testEvents()
+	for _, e := range events {
+		e.MsgId = e.Id
+	}
+	// NOTE: the sinks are at-least-once, a redelivered deployment may be stored twice
+	redelivered := *events[3]
+	d := FromEvents(append(events, &redelivered))
+	if len(d.Deployments) != 2 {
+		t.Errorf("deployments: %+v", d.Deployments)
+	}
+}
+
+func TestIncidentsPerRepository(t *testing.T) {
+	d := FromEvents([]*shared.EventRecord{
+		{Source: "github", EventType: "issues", Id: "a10", Repository: "org/a", Metadata: `{
+			"issue": {"number": 10, "created_at": "2024-01-03T06:00:00Z", "closed_at": "2024-01-03T08:00:00Z",
+			"labels": [{"name": "Incident"}]}}`},
+		{Source: "github", EventType: "issues", Id: "b10", Repository: "org/b", Metadata: `{
+			"issue": {"number": 10, "created_at": "2024-01-04T06:00:00Z", "closed_at": "2024-01-04T07:00:00Z",
+			"labels": [{"name": "Incident"}]}}`},
+	})
+
+	if len(d.Incidents) != 2 {
+		t.Fatalf("incidents: %+v", d.Incidents)
+	}
+	for i, want := range []string{"org/a", "org/b"} {
+		if got := d.Incidents[i]; got.IncidentID != "10" || got.Repository != want {
+			t.Errorf("incident %d: %+v", i, got)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := FromEvents(testEvents()).Summarize(day(1, 0), day(5, 0))
+
+	if s.Changes != 3 || s.Deployments != 2 || s.DaysWithDeployments != 2 {
+		t.Errorf("counts: %+v", s)
+	}
+	if s.DeploymentsPerDay != 0.5 {
+		t.Errorf("deployments per day: %v", s.DeploymentsPerDay)
+	}
+	// lead times: c1 12h, c2 6h, c3 24h
+	if s.LeadTimeSamples != 3 || s.LeadTimeMedian != 12*time.Hour {
+		t.Errorf("lead time: %d samples, median %v", s.LeadTimeSamples, s.LeadTimeMedian)
+	}
+	if want := 21*time.Hour + 36*time.Minute; s.LeadTimeP90 != want {
+		t.Errorf("lead time p90: %v, want %v", s.LeadTimeP90, want)
+	}
+	if s.FailedDeployments != 1 || s.ChangeFailureRate != 0.5 {
+		t.Errorf("change failure rate: %d, %v", s.FailedDeployments, s.ChangeFailureRate)
+	}
+	// restore times: github 8h, pagerduty 0
+	if s.Incidents != 2 || s.ResolvedIncidents != 2 || s.TimeToRestoreMedian != 4*time.Hour {
+		t.Errorf("time to restore: %+v", s)
+	}
+
+	empty := FromEvents(testEvents()).Summarize(day(10, 0), day(11, 0))
+	if empty.Deployments != 0 || empty.ChangeFailureRate != 0 || empty.LeadTimeMedian != 0 {
+		t.Errorf("empty window: %+v", empty)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []time.Duration{4, 1, 3, 2}
+	cases := map[float64]time.Duration{0: 1, 0.5: 2, 1: 4}
+	for p, want := range cases {
+		if got := Percentile(values, p); got != want {
+			t.Errorf("p%v: %v, want %v", p, got, want)
+		}
+	}
+	if Percentile(nil, 0.5) != 0 {
+		t.Errorf("empty percentile")
+	}
+}
+
+func TestIncidentJSON(t *testing.T) {
+	b, err := json.Marshal(Incident{Source: "github", IncidentID: "1", TimeCreated: day(1, 0), Changes: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"source":"github","incident_id":"1","time_created":"2024-01-01T00:00:00Z","changes":[],"time_resolved":null}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	values := []time.Duration{0, time.Hour, 2 * time.Hour, 48 * time.Hour}
+	got := Histogram(values, []time.Duration{time.Hour, 24 * time.Hour})
+	want := []Bucket{{time.Hour, 2}, {24 * time.Hour, 1}, {Inf, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+type Granularity string
+
+const (
+	// GranularityNone reports the whole window as one period.
+	GranularityNone    Granularity = ""
+	GranularityDaily   Granularity = "daily"
+	GranularityWeekly  Granularity = "weekly"
+	GranularityMonthly Granularity = "monthly"
+)
+
+func ParseGranularity(s string) (Granularity, error) {
+	switch g := Granularity(s); g {
+	case GranularityNone, GranularityDaily, GranularityWeekly, GranularityMonthly:
+		return g, nil
+	case "none", "total":
+		return GranularityNone, nil
+	}
+	return "", fmt.Errorf("unknown granularity %q, expected daily, weekly or monthly", s)
+}
+
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// Periods splits [from, to) at UTC day, ISO week (Monday) or month boundaries.
+// The first and last periods are clipped to the window.
+func Periods(from, to time.Time, g Granularity) []Period {
+	if !from.Before(to) {
+		return nil
+	}
+	if g == GranularityNone {
+		return []Period{{From: from, To: to}}
+	}
+
+	var periods []Period
+	start := from
+	for start.Before(to) {
+		end := nextBoundary(start.UTC(), g)
+		if end.After(to) {
+			end = to
+		}
+		periods = append(periods, Period{From: start, To: end})
+		start = end
+	}
+	return periods
+}
+
+func nextBoundary(t time.Time, g Granularity) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch g {
+	case GranularityWeekly:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, 7-offset)
+	case GranularityMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	default:
+		return day.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPeriods(t *testing.T) {
+	// 2024-01-03 is a Wednesday
+	from := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		g     Granularity
+		count int
+		first Period
+	}{
+		{GranularityNone, 1, Period{from, to}},
+		{GranularityDaily, 34, Period{from, time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)}},
+		{GranularityWeekly, 6, Period{from, time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)}},
+		{GranularityMonthly, 2, Period{from, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, c := range cases {
+		t.Run(string(c.g), func(t *testing.T) {
+			periods := Periods(from, to, c.g)
+			if len(periods) != c.count {
+				t.Fatalf("periods: %d, want %d", len(periods), c.count)
+			}
+			if !reflect.DeepEqual(periods[0], c.first) {
+				t.Errorf("first: %+v, want %+v", periods[0], c.first)
+			}
+			if !periods[len(periods)-1].To.Equal(to) {
+				t.Errorf("last: %+v", periods[len(periods)-1])
+			}
+		})
+	}
+
+	if _, err := ParseGranularity("hourly"); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestForRepository(t *testing.T) {
+	d := FromEvents(testEvents())
+	if got := d.Repositories(); !reflect.DeepEqual(got, []string{"", "org/app"}) {
+		t.Errorf("repositories: %v", got)
+	}
+	app := d.ForRepository("org/app")
+	if len(app.Changes) != 2 || len(app.Deployments) != 0 {
+		t.Errorf("org/app: %+v", app)
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import "time"
+
+// Row is the JSON form of a Summary with its classification. Durations are in seconds.
+type Row struct {
+	PeriodStart                time.Time `json:"period_start"`
+	PeriodEnd                  time.Time `json:"period_end"`
+	Repository                 string    `json:"repository,omitempty"`
+	Changes                    int       `json:"changes"`
+	Deployments                int       `json:"deployments"`
+	DaysWithDeployments        int       `json:"days_with_deployments"`
+	DeploymentsPerDay          float64   `json:"deployments_per_day"`
+	LeadTimeSamples            int       `json:"lead_time_samples"`
+	LeadTimeMedianSeconds      int64     `json:"lead_time_median_seconds"`
+	LeadTimeP90Seconds         int64     `json:"lead_time_p90_seconds"`
+	FailedDeployments          int       `json:"failed_deployments"`
+	ChangeFailureRate          float64   `json:"change_failure_rate"`
+	Incidents                  int       `json:"incidents"`
+	ResolvedIncidents          int       `json:"resolved_incidents"`
+	TimeToRestoreMedianSeconds int64     `json:"time_to_restore_median_seconds"`
+	TimeToRestoreP90Seconds    int64     `json:"time_to_restore_p90_seconds"`
+
+	Classification Classification `json:"classification"`
+}
+
+func NewRow(repository string, s Summary, t Thresholds) Row {
+	return Row{
+		PeriodStart:                s.From.UTC(),
+		PeriodEnd:                  s.To.UTC(),
+		Repository:                 repository,
+		Changes:                    s.Changes,
+		Deployments:                s.Deployments,
+		DaysWithDeployments:        s.DaysWithDeployments,
+		DeploymentsPerDay:          s.DeploymentsPerDay,
+		LeadTimeSamples:            s.LeadTimeSamples,
+		LeadTimeMedianSeconds:      int64(s.LeadTimeMedian.Seconds()),
+		LeadTimeP90Seconds:         int64(s.LeadTimeP90.Seconds()),
+		FailedDeployments:          s.FailedDeployments,
+		ChangeFailureRate:          s.ChangeFailureRate,
+		Incidents:                  s.Incidents,
+		ResolvedIncidents:          s.ResolvedIncidents,
+		TimeToRestoreMedianSeconds: int64(s.TimeToRestoreMedian.Seconds()),
+		TimeToRestoreP90Seconds:    int64(s.TimeToRestoreP90.Seconds()),
+		Classification:             t.Classify(s),
+	}
+}
+
+type ReportOptions struct {
+	From, To    time.Time
+	Granularity Granularity
+	// Repositories restricts the report to these repositories, one row each per period.
+	Repositories []string
+	// ByRepository reports every repository of the dataset separately.
+	ByRepository bool
+	Thresholds   Thresholds
+}
+
+// Report summarizes every period, per repository when requested.
+func (d *Dataset) Report(opts ReportOptions) []Row {
+	repos := opts.Repositories
+	if len(repos) == 0 && opts.ByRepository {
+		repos = d.Repositories()
+	}
+
+	var rows []Row
+	for _, p := range Periods(opts.From, opts.To, opts.Granularity) {
+		if len(repos) == 0 {
+			rows = append(rows, NewRow("", d.Summarize(p.From, p.To), opts.Thresholds))
+			continue
+		}
+		for _, repo := range repos {
+			rows = append(rows, NewRow(repo, d.ForRepository(repo).Summarize(p.From, p.To), opts.Thresholds))
+		}
+	}
+	return rows
+}
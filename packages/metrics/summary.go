@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Summary holds the four keys over the half-open window [From, To).
+type Summary struct {
+	From time.Time
+	To   time.Time
+
+	Changes int
+
+	// Deployments is the number of deployments in the window.
+	Deployments         int
+	DaysWithDeployments int
+	// DeploymentsPerDay is Deployments divided by the length of the window in days.
+	DeploymentsPerDay float64
+
+	// LeadTimeSamples is the number of deployed changes with a known commit time.
+	LeadTimeSamples int
+	LeadTimeMedian  time.Duration
+	LeadTimeP90     time.Duration
+
+	// FailedDeployments have a change named as the root cause of an incident.
+	FailedDeployments int
+	// ChangeFailureRate is FailedDeployments / Deployments, 0 without deployments.
+	ChangeFailureRate float64
+
+	Incidents           int
+	ResolvedIncidents   int
+	TimeToRestoreMedian time.Duration
+	TimeToRestoreP90    time.Duration
+}
+
+func inWindow(t, from, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+// Summarize computes the four keys for events created in [from, to).
+func (d *Dataset) Summarize(from, to time.Time) Summary {
+	s := Summary{From: from, To: to}
+
+	for _, c := range d.Changes {
+		if inWindow(c.TimeCreated, from, to) {
+			s.Changes++
+		}
+	}
+
+	failedChanges := map[string]bool{}
+	for _, i := range d.Incidents {
+		for _, c := range i.Changes {
+			failedChanges[c] = true
+		}
+	}
+
+	days := map[string]bool{}
+	for _, dep := range d.Deployments {
+		if !inWindow(dep.TimeCreated, from, to) {
+			continue
+		}
+		s.Deployments++
+		days[dep.TimeCreated.UTC().Format(time.DateOnly)] = true
+
+		failed := false
+		for _, c := range dep.Changes {
+			if failedChanges[c] {
+				failed = true
+			}
+		}
+		if failed {
+			s.FailedDeployments++
+		}
+	}
+	s.DaysWithDeployments = len(days)
+	if windowDays := to.Sub(from).Hours() / 24; windowDays > 0 {
+		s.DeploymentsPerDay = float64(s.Deployments) / windowDays
+	}
+	if s.Deployments > 0 {
+		s.ChangeFailureRate = float64(s.FailedDeployments) / float64(s.Deployments)
+	}
+	leadTimes := d.LeadTimes(from, to)
+	s.LeadTimeSamples = len(leadTimes)
+	s.LeadTimeMedian = Percentile(leadTimes, 0.5)
+	s.LeadTimeP90 = Percentile(leadTimes, 0.9)
+
+	for _, i := range d.Incidents {
+		if inWindow(i.TimeCreated, from, to) {
+			s.Incidents++
+		}
+	}
+	restoreTimes := d.RestoreTimes(from, to)
+	s.ResolvedIncidents = len(restoreTimes)
+	s.TimeToRestoreMedian = Percentile(restoreTimes, 0.5)
+	s.TimeToRestoreP90 = Percentile(restoreTimes, 0.9)
+
+	return s
+}
+
+// LeadTimes returns the time from commit to deployment of every change
+// deployed in [from, to). Changes without a commit time are skipped.
+func (d *Dataset) LeadTimes(from, to time.Time) []time.Duration {
+	changeTimes := map[string]time.Time{}
+	for _, c := range d.Changes {
+		changeTimes[c.ChangeID] = c.TimeCreated
+	}
+
+	var leadTimes []time.Duration
+	for _, dep := range d.Deployments {
+		if !inWindow(dep.TimeCreated, from, to) {
+			continue
+		}
+		for _, c := range dep.Changes {
+			if t, ok := changeTimes[c]; ok && !t.IsZero() {
+				leadTimes = append(leadTimes, dep.TimeCreated.Sub(t))
+			}
+		}
+	}
+	return leadTimes
+}
+
+// RestoreTimes returns the time to restore of every resolved incident
+// created in [from, to).
+func (d *Dataset) RestoreTimes(from, to time.Time) []time.Duration {
+	var restoreTimes []time.Duration
+	for _, i := range d.Incidents {
+		if !inWindow(i.TimeCreated, from, to) || i.TimeResolved.IsZero() {
+			continue
+		}
+		restoreTimes = append(restoreTimes, i.TimeResolved.Sub(i.TimeCreated))
+	}
+	return restoreTimes
+}
+
+// Percentile interpolates linearly between the closest ranks like PERCENTILE_CONT.
+// It returns 0 for no values.
+func Percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := p * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	frac := rank - float64(lo)
+	return sorted[lo] + time.Duration(frac*float64(sorted[hi]-sorted[lo]))
+}
@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type Tier string
+
+const (
+	TierElite  Tier = "elite"
+	TierHigh   Tier = "high"
+	TierMedium Tier = "medium"
+	TierLow    Tier = "low"
+	// TierUnknown is used when a metric has no data in the period, e.g. no incidents.
+	TierUnknown Tier = "unknown"
+)
+
+var tierRank = map[Tier]int{TierElite: 0, TierHigh: 1, TierMedium: 2, TierLow: 3}
+
+// Duration is a time.Duration that reads and writes JSON as "24h".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Thresholds are the lower bounds (deployment frequency) or upper bounds (the others)
+// of the elite, high and medium tiers. Anything beyond medium is low.
+type Thresholds struct {
+	// DeploymentFrequency is in deployments per day.
+	DeploymentFrequency RateBands     `json:"deployment_frequency"`
+	LeadTime            DurationBands `json:"lead_time"`
+	// ChangeFailureRate is a ratio between 0 and 1.
+	ChangeFailureRate RateBands     `json:"change_failure_rate"`
+	TimeToRestore     DurationBands `json:"time_to_restore"`
+}
+
+type RateBands struct {
+	Elite  float64 `json:"elite"`
+	High   float64 `json:"high"`
+	Medium float64 `json:"medium"`
+}
+
+type DurationBands struct {
+	Elite  Duration `json:"elite"`
+	High   Duration `json:"high"`
+	Medium Duration `json:"medium"`
+}
+
+// DefaultThresholds follows the State of DevOps performance bands:
+// deploying daily / weekly / monthly, lead time and restore within a day / week / month
+// (restore within an hour for elite), and change failure rates of 15% / 30% / 45%.
+func DefaultThresholds() Thresholds {
+	var t Thresholds
+	t.DeploymentFrequency.Elite = 1
+	t.DeploymentFrequency.High = 1.0 / 7
+	t.DeploymentFrequency.Medium = 1.0 / 30
+	t.LeadTime.Elite = Duration(24 * time.Hour)
+	t.LeadTime.High = Duration(7 * 24 * time.Hour)
+	t.LeadTime.Medium = Duration(30 * 24 * time.Hour)
+	t.ChangeFailureRate.Elite = 0.15
+	t.ChangeFailureRate.High = 0.30
+	t.ChangeFailureRate.Medium = 0.45
+	t.TimeToRestore.Elite = Duration(time.Hour)
+	t.TimeToRestore.High = Duration(24 * time.Hour)
+	t.TimeToRestore.Medium = Duration(7 * 24 * time.Hour)
+	return t
+}
+
+// LoadThresholds reads thresholds from a JSON file. Omitted values keep their defaults.
+func LoadThresholds(path string) (Thresholds, error) {
+	t := DefaultThresholds()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return t, fmt.Errorf("error reading thresholds %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return t, fmt.Errorf("error unmarshalling thresholds %s: %w", path, err)
+	}
+	return t, nil
+}
+
+type MetricTier struct {
+	Tier   Tier   `json:"tier"`
+	Reason string `json:"reason"`
+}
+
+type Classification struct {
+	// Overall is the lowest tier among the metrics that have data.
+	Overall             Tier       `json:"overall"`
+	DeploymentFrequency MetricTier `json:"deployment_frequency"`
+	LeadTime            MetricTier `json:"lead_time"`
+	ChangeFailureRate   MetricTier `json:"change_failure_rate"`
+	TimeToRestore       MetricTier `json:"time_to_restore"`
+}
+
+func (t Thresholds) Classify(s Summary) Classification {
+	c := Classification{
+		DeploymentFrequency: t.classifyDeploymentFrequency(s),
+		LeadTime:            t.classifyLeadTime(s),
+		ChangeFailureRate:   t.classifyChangeFailureRate(s),
+		TimeToRestore:       t.classifyTimeToRestore(s),
+	}
+
+	c.Overall = TierUnknown
+	for _, m := range []MetricTier{c.DeploymentFrequency, c.LeadTime, c.ChangeFailureRate, c.TimeToRestore} {
+		if m.Tier == TierUnknown {
+			continue
+		}
+		if c.Overall == TierUnknown || tierRank[m.Tier] > tierRank[c.Overall] {
+			c.Overall = m.Tier
+		}
+	}
+	return c
+}
+
+func (t Thresholds) classifyDeploymentFrequency(s Summary) MetricTier {
+	b := t.DeploymentFrequency
+	v := s.DeploymentsPerDay
+	switch {
+	case v >= b.Elite:
+		return MetricTier{TierElite, fmt.Sprintf("%.2f deployments/day, at least %.2f", v, b.Elite)}
+	case v >= b.High:
+		return MetricTier{TierHigh, fmt.Sprintf("%.2f deployments/day, at least %.2f", v, b.High)}
+	case v >= b.Medium:
+		return MetricTier{TierMedium, fmt.Sprintf("%.2f deployments/day, at least %.2f", v, b.Medium)}
+	}
+	return MetricTier{TierLow, fmt.Sprintf("%.2f deployments/day, below %.2f", v, b.Medium)}
+}
+
+func (t Thresholds) classifyLeadTime(s Summary) MetricTier {
+	if s.LeadTimeSamples == 0 {
+		return MetricTier{TierUnknown, "no deployed changes"}
+	}
+	return classifyDuration("median lead time", s.LeadTimeMedian, t.LeadTime)
+}
+
+func (t Thresholds) classifyTimeToRestore(s Summary) MetricTier {
+	if s.ResolvedIncidents == 0 {
+		return MetricTier{TierUnknown, "no resolved incidents"}
+	}
+	return classifyDuration("median time to restore", s.TimeToRestoreMedian, t.TimeToRestore)
+}
+
+func classifyDuration(name string, v time.Duration, b DurationBands) MetricTier {
+	elite, high, medium := b.Elite, b.High, b.Medium
+	switch {
+	case v <= time.Duration(elite):
+		return MetricTier{TierElite, fmt.Sprintf("%s %s, within %s", name, v, time.Duration(elite))}
+	case v <= time.Duration(high):
+		return MetricTier{TierHigh, fmt.Sprintf("%s %s, within %s", name, v, time.Duration(high))}
+	case v <= time.Duration(medium):
+		return MetricTier{TierMedium, fmt.Sprintf("%s %s, within %s", name, v, time.Duration(medium))}
+	}
+	return MetricTier{TierLow, fmt.Sprintf("%s %s, over %s", name, v, time.Duration(medium))}
+}
+
+func (t Thresholds) classifyChangeFailureRate(s Summary) MetricTier {
+	if s.Deployments == 0 {
+		return MetricTier{TierUnknown, "no deployments"}
+	}
+	b := t.ChangeFailureRate
+	v := s.ChangeFailureRate
+	switch {
+	case v <= b.Elite:
+		return MetricTier{TierElite, fmt.Sprintf("%.1f%% of deployments failed, at most %.1f%%", v*100, b.Elite*100)}
+	case v <= b.High:
+		return MetricTier{TierHigh, fmt.Sprintf("%.1f%% of deployments failed, at most %.1f%%", v*100, b.High*100)}
+	case v <= b.Medium:
+		return MetricTier{TierMedium, fmt.Sprintf("%.1f%% of deployments failed, at most %.1f%%", v*100, b.Medium*100)}
+	}
+	return MetricTier{TierLow, fmt.Sprintf("%.1f%% of deployments failed, over %.1f%%", v*100, b.Medium*100)}
+}
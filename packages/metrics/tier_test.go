@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	thresholds := DefaultThresholds()
+
+	cases := []struct {
+		name    string
+		summary Summary
+		want    Classification
+	}{
+		{
+			name: "elite",
+			summary: Summary{
+				Deployments: 20, DeploymentsPerDay: 2,
+				LeadTimeSamples: 10, LeadTimeMedian: 3 * time.Hour,
+				ChangeFailureRate: 0.05,
+				ResolvedIncidents: 1, TimeToRestoreMedian: 30 * time.Minute,
+			},
+			want: Classification{
+				Overall:             TierElite,
+				DeploymentFrequency: MetricTier{Tier: TierElite},
+				LeadTime:            MetricTier{Tier: TierElite},
+				ChangeFailureRate:   MetricTier{Tier: TierElite},
+				TimeToRestore:       MetricTier{Tier: TierElite},
+			},
+		},
+		{
+			name: "overall is the weakest metric",
+			summary: Summary{
+				Deployments: 2, DeploymentsPerDay: 2.0 / 28,
+				LeadTimeSamples: 4, LeadTimeMedian: 3 * 24 * time.Hour,
+				ChangeFailureRate: 0.5,
+			},
+			want: Classification{
+				Overall:             TierLow,
+				DeploymentFrequency: MetricTier{Tier: TierMedium},
+				LeadTime:            MetricTier{Tier: TierHigh},
+				ChangeFailureRate:   MetricTier{Tier: TierLow},
+				TimeToRestore:       MetricTier{Tier: TierUnknown},
+			},
+		},
+		{
+			name:    "no data",
+			summary: Summary{},
+			want: Classification{
+				Overall:             TierLow,
+				DeploymentFrequency: MetricTier{Tier: TierLow},
+				LeadTime:            MetricTier{Tier: TierUnknown},
+				ChangeFailureRate:   MetricTier{Tier: TierUnknown},
+				TimeToRestore:       MetricTier{Tier: TierUnknown},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := thresholds.Classify(c.summary)
+			if got.Overall != c.want.Overall ||
+				got.DeploymentFrequency.Tier != c.want.DeploymentFrequency.Tier ||
+				got.LeadTime.Tier != c.want.LeadTime.Tier ||
+				got.ChangeFailureRate.Tier != c.want.ChangeFailureRate.Tier ||
+				got.TimeToRestore.Tier != c.want.TimeToRestore.Tier {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+			for _, m := range []MetricTier{got.DeploymentFrequency, got.LeadTime, got.ChangeFailureRate, got.TimeToRestore} {
+				if m.Reason == "" {
+					t.Errorf("missing reason: %+v", m)
+				}
+			}
+		})
+	}
+
+	if got := thresholds.Classify(cases[1].summary).ChangeFailureRate.Reason; got != "50.0% of deployments failed, over 45.0%" {
+		t.Errorf("reason: %s", got)
+	}
+}
+
+func TestLoadThresholds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thresholds.json")
+	err := os.WriteFile(path, []byte(`{"lead_time": {"elite": "12h"}, "change_failure_rate": {"medium": 0.6}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadThresholds(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultThresholds()
+	want.LeadTime.Elite = Duration(12 * time.Hour)
+	want.ChangeFailureRate.Medium = 0.6
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,162 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeJSON decodes a JSON object keeping numbers as json.Number,
+// so that large ids (e.g. GitHub ids above 2^53) keep their precision.
+func DecodeJSON(b []byte) (map[string]interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var m map[string]interface{}
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LookupString returns the value at keys as a string. Numbers are formatted without exponent.
+func LookupString(m map[string]interface{}, keys ...string) (string, error) {
+	v, err := walk(m, keys)
+	if err != nil {
+		return "", err
+	}
+	s, err := ToString(v)
+	if err != nil {
+		return "", fmt.Errorf("key %s: %w", strings.Join(keys, "."), err)
+	}
+	return s, nil
+}
+
+func LookupInt64(m map[string]interface{}, keys ...string) (int64, error) {
+	v, err := walk(m, keys)
+	if err != nil {
+		return 0, err
+	}
+	n, err := ToInt64(v)
+	if err != nil {
+		return 0, fmt.Errorf("key %s: %w", strings.Join(keys, "."), err)
+	}
+	return n, nil
+}
+
+func LookupBool(m map[string]interface{}, keys ...string) (bool, error) {
+	v, err := walk(m, keys)
+	if err != nil {
+		return false, err
+	}
+	b, err := ToBool(v)
+	if err != nil {
+		return false, fmt.Errorf("key %s: %w", strings.Join(keys, "."), err)
+	}
+	return b, nil
+}
+
+// LookupTime returns the value at keys as a time. See ToTime for the accepted formats.
+func LookupTime(m map[string]interface{}, keys ...string) (time.Time, error) {
+	v, err := walk(m, keys)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := ToTime(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("key %s: %w", strings.Join(keys, "."), err)
+	}
+	return t, nil
+}
+
+func ToString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		if !strings.ContainsAny(string(v), ".eE") {
+			return string(v), nil
+		}
+		f, _, err := big.ParseFloat(string(v), 10, 256, big.ToNearestEven)
+		if err != nil {
+			return "", fmt.Errorf("invalid number %s: %w", v, err)
+		}
+		return f.Text('f', -1), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("%v (%T) can not be converted to string", v, v)
+}
+
+func ToInt64(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %s: %w", v, err)
+		}
+		return floatToInt64(f)
+	case float64:
+		return floatToInt64(v)
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer %q: %w", v, err)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("%v (%T) can not be converted to int64", v, v)
+}
+
+func floatToInt64(f float64) (int64, error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("%v is not an int64", f)
+	}
+	return int64(f), nil
+}
+
+func ToBool(v interface{}) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("invalid bool %q: %w", v, err)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("%v (%T) can not be converted to bool", v, v)
+}
+
+// ToTime accepts the formats of ParseTimestamp and Unix epoch numbers.
+func ToTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return ParseTimestamp(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid number %s: %w", v, err)
+		}
+		return epochTime(f), nil
+	case float64:
+		return epochTime(v), nil
+	}
+	return time.Time{}, fmt.Errorf("%v (%T) can not be converted to time", v, v)
+}
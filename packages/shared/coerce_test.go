@@ -0,0 +1,92 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoercingLookups(t *testing.T) {
+	m, err := DecodeJSON([]byte(`{
+		"id": 9007199254740993,
+		"float": 1.5,
+		"exp": 1e3,
+		"str": "42",
+		"bool": true,
+		"boolStr": "false",
+		"rfc3339": "2022-01-05T04:36:28.123Z",
+		"gitlab": "2022-01-05 04:36:28 -0800",
+		"epoch": 1641357388,
+		"epochMillis": 1641357388123
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("LookupString", func(t *testing.T) {
+		cases := map[string]string{
+			"id":    "9007199254740993",
+			"float": "1.5",
+			"exp":   "1000",
+			"str":   "42",
+			"bool":  "true",
+		}
+		for key, want := range cases {
+			if got, err := LookupString(m, key); err != nil || got != want {
+				t.Errorf("%s: %q, %v, want %q", key, got, err, want)
+			}
+		}
+		if s, err := LookupString(map[string]interface{}{"id": float64(1985130141)}, "id"); err != nil || s != "1985130141" {
+			t.Errorf("float64 id: %q, %v", s, err)
+		}
+	})
+
+	t.Run("LookupInt64", func(t *testing.T) {
+		if n, err := LookupInt64(m, "id"); err != nil || n != 9007199254740993 {
+			t.Errorf("id: %d, %v", n, err)
+		}
+		if n, err := LookupInt64(m, "str"); err != nil || n != 42 {
+			t.Errorf("str: %d, %v", n, err)
+		}
+		if _, err := LookupInt64(m, "float"); err == nil {
+			t.Errorf("float: expected error")
+		}
+	})
+
+	t.Run("LookupBool", func(t *testing.T) {
+		if b, err := LookupBool(m, "bool"); err != nil || !b {
+			t.Errorf("bool: %v, %v", b, err)
+		}
+		if b, err := LookupBool(m, "boolStr"); err != nil || b {
+			t.Errorf("boolStr: %v, %v", b, err)
+		}
+		if _, err := LookupBool(m, "id"); err == nil {
+			t.Errorf("id: expected error")
+		}
+	})
+
+	t.Run("LookupTime", func(t *testing.T) {
+		cases := map[string]time.Time{
+			"rfc3339":     time.Date(2022, 1, 5, 4, 36, 28, 123000000, time.UTC),
+			"gitlab":      time.Date(2022, 1, 5, 12, 36, 28, 0, time.UTC),
+			"epoch":       time.Date(2022, 1, 5, 4, 36, 28, 0, time.UTC),
+			"epochMillis": time.Date(2022, 1, 5, 4, 36, 28, 123000000, time.UTC),
+		}
+		for key, want := range cases {
+			if got, err := LookupTime(m, key); err != nil || !got.Equal(want) {
+				t.Errorf("%s: %v, %v, want %v", key, got, err, want)
+			}
+		}
+		if _, err := LookupTime(m, "boolStr"); err == nil {
+			t.Errorf("boolStr: expected error")
+		}
+	})
+
+	t.Run("LookupMapE with json.Number", func(t *testing.T) {
+		if n, err := LookupMapE[int64](m, "id"); err != nil || n != 9007199254740993 {
+			t.Errorf("id: %d, %v", n, err)
+		}
+		if f, err := LookupMapE[float64](m, "float"); err != nil || f != 1.5 {
+			t.Errorf("float: %v, %v", f, err)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+// Package envelope defines the normalized message producers publish for the parsers:
+// a CloudEvents 1.0 event carrying the original webhook payload and headers.
+//
+// On Pub/Sub the event uses the binary content mode of the CloudEvents Pub/Sub binding:
+// the context attributes are message attributes prefixed with "ce-" and the payload is the
+// message data. The webhook headers stay in the "headers" attribute as JSON, which is what
+// producers published before the envelope existed, so older parsers keep working.
+//
+// Versioning: the "fourkeysversion" extension carries SchemaVersion as "<major>.<minor>".
+// Adding optional attributes bumps the minor version and decoders ignore what they do not
+// know. Removing or changing the meaning of an attribute bumps the major version, and
+// Decode rejects majors newer than Major.
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SpecVersion = "1.0"
+
+	Major         = 1
+	Minor         = 0
+	SchemaVersion = "1.0"
+
+	// TypeWebhook is the type of events that wrap a webhook request as received.
+	TypeWebhook = "dev.fourkeys.webhook.received"
+
+	attributePrefix  = "ce-"
+	headersAttribute = "headers"
+	versionExtension = "fourkeysversion"
+)
+
+var (
+	ErrNotCloudEvent      = errors.New("not a CloudEvent")
+	ErrUnsupportedVersion = errors.New("unsupported envelope version")
+)
+
+type Event struct {
+	ID string
+	// Source is the fourkeys source name, e.g. "github" or "gitlab".
+	Source string
+	Type   string
+	Time   time.Time
+	// Subject is the provider's event type, e.g. "push" for X-Github-Event: push.
+	Subject         string
+	DataContentType string
+	SchemaVersion   string
+	// Headers are the original webhook request headers.
+	Headers map[string][]string
+	Data    []byte
+}
+
+// NewWebhookEvent wraps a webhook request received from source.
+func NewWebhookEvent(id, source, subject string, headers map[string][]string, body []byte) *Event {
+	return &Event{
+		ID:              id,
+		Source:          source,
+		Type:            TypeWebhook,
+		Time:            time.Now().UTC(),
+		Subject:         subject,
+		DataContentType: "application/json",
+		SchemaVersion:   SchemaVersion,
+		Headers:         headers,
+		Data:            body,
+	}
+}
+
+func (e *Event) validate() error {
+	var missing []string
+	if e.ID == "" {
+		missing = append(missing, "id")
+	}
+	if e.Source == "" {
+		missing = append(missing, "source")
+	}
+	if e.Type == "" {
+		missing = append(missing, "type")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required attributes: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Encode returns the Pub/Sub attributes and data of e in binary content mode.
+func Encode(e *Event) (map[string]string, []byte, error) {
+	if err := e.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	attrs := map[string]string{
+		attributePrefix + "specversion":    SpecVersion,
+		attributePrefix + "id":             e.ID,
+		attributePrefix + "source":         e.Source,
+		attributePrefix + "type":           e.Type,
+		attributePrefix + versionExtension: e.schemaVersion(),
+	}
+	if !e.Time.IsZero() {
+		attrs[attributePrefix+"time"] = e.Time.UTC().Format(time.RFC3339Nano)
+	}
+	if e.Subject != "" {
+		attrs[attributePrefix+"subject"] = e.Subject
+	}
+	if e.DataContentType != "" {
+		attrs["content-type"] = e.DataContentType
+	}
+	if e.Headers != nil {
+		headers, err := json.Marshal(e.Headers)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error marshalling headers: %w", err)
+		}
+		attrs[headersAttribute] = string(headers)
+	}
+	return attrs, e.Data, nil
+}
+
+// IsCloudEvent reports whether attrs carry a binary mode CloudEvent.
+func IsCloudEvent(attrs map[string]string) bool {
+	_, ok := attrs[attributePrefix+"specversion"]
+	return ok
+}
+
+// Decode reads a binary content mode event from Pub/Sub attributes and data.
+// It returns ErrNotCloudEvent for messages published without the envelope.
+func Decode(attrs map[string]string, data []byte) (*Event, error) {
+	if !IsCloudEvent(attrs) {
+		return nil, ErrNotCloudEvent
+	}
+	if v := attrs[attributePrefix+"specversion"]; v != SpecVersion {
+		return nil, fmt.Errorf("unsupported CloudEvents specversion %q", v)
+	}
+
+	e := &Event{
+		ID:              attrs[attributePrefix+"id"],
+		Source:          attrs[attributePrefix+"source"],
+		Type:            attrs[attributePrefix+"type"],
+		Subject:         attrs[attributePrefix+"subject"],
+		DataContentType: attrs["content-type"],
+		SchemaVersion:   attrs[attributePrefix+versionExtension],
+		Data:            data,
+	}
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(e.SchemaVersion); err != nil {
+		return nil, err
+	}
+	if v, ok := attrs[attributePrefix+"time"]; ok {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q: %w", v, err)
+		}
+		e.Time = t
+	}
+	if v, ok := attrs[headersAttribute]; ok {
+		if err := json.Unmarshal([]byte(v), &e.Headers); err != nil {
+			return nil, fmt.Errorf("error unmarshalling headers: %w", err)
+		}
+	}
+	return e, nil
+}
+
+func (e *Event) schemaVersion() string {
+	if e.SchemaVersion == "" {
+		return SchemaVersion
+	}
+	return e.SchemaVersion
+}
+
+// checkVersion accepts any version with a major up to Major. A missing version is 1.0.
+func checkVersion(v string) error {
+	if v == "" {
+		return nil
+	}
+	major, _, _ := strings.Cut(v, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrUnsupportedVersion, v)
+	}
+	if n < 1 || n > Major {
+		return fmt.Errorf("%w: %q", ErrUnsupportedVersion, v)
+	}
+	return nil
+}
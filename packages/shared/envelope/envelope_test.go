@@ -0,0 +1,88 @@
+package envelope
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testEvent() *Event {
+	e := NewWebhookEvent("delivery-1", "github", "push",
+		map[string][]string{"X-Github-Event": {"push"}},
+		[]byte(`{"ref":"refs/heads/main"}`),
+	)
+	e.Time = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return e
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	attrs, data, err := Encode(testEvent())
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if attrs["headers"] != `{"X-Github-Event":["push"]}` {
+		t.Errorf("headers attribute: %s", attrs["headers"])
+	}
+
+	got, err := Decode(attrs, data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, testEvent()) {
+		t.Errorf("got %+v, want %+v", got, testEvent())
+	}
+}
+
+func TestStructuredRoundTrip(t *testing.T) {
+	b, err := MarshalStructured(testEvent())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got, err := UnmarshalStructured(b)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(got, testEvent()) {
+		t.Errorf("got %+v, want %+v", got, testEvent())
+	}
+
+	binary := testEvent()
+	binary.DataContentType = "application/octet-stream"
+	binary.Data = []byte{0xff, 0x00}
+	b, err = MarshalStructured(binary)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got, err = UnmarshalStructured(b)
+	if err != nil || !reflect.DeepEqual(got.Data, binary.Data) {
+		t.Errorf("data_base64: %v, %v", got, err)
+	}
+}
+
+func TestDecodeVersions(t *testing.T) {
+	attrs, data, err := Encode(testEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []string{"1.0", "1.7", ""} {
+		attrs["ce-fourkeysversion"] = v
+		if _, err := Decode(attrs, data); err != nil {
+			t.Errorf("%q: %v", v, err)
+		}
+	}
+	for _, v := range []string{"2.0", "0.1", "x"} {
+		attrs["ce-fourkeysversion"] = v
+		if _, err := Decode(attrs, data); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("%q: %v", v, err)
+		}
+	}
+
+	if _, err := Decode(map[string]string{"headers": "{}"}, data); !errors.Is(err, ErrNotCloudEvent) {
+		t.Errorf("legacy message: %v", err)
+	}
+	if _, err := Decode(map[string]string{"ce-specversion": "1.0", "ce-id": "x"}, data); err == nil {
+		t.Errorf("expected missing attributes error")
+	}
+}
@@ -0,0 +1,112 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// structuredEvent is the JSON event format (structured content mode), for producers that
+// can not set message attributes or deliver over HTTP.
+type structuredEvent struct {
+	SpecVersion     string              `json:"specversion"`
+	ID              string              `json:"id"`
+	Source          string              `json:"source"`
+	Type            string              `json:"type"`
+	Time            *time.Time          `json:"time,omitempty"`
+	Subject         string              `json:"subject,omitempty"`
+	DataContentType string              `json:"datacontenttype,omitempty"`
+	Version         string              `json:"fourkeysversion,omitempty"`
+	Headers         map[string][]string `json:"fourkeysheaders,omitempty"`
+	Data            json.RawMessage     `json:"data,omitempty"`
+	DataBase64      []byte              `json:"data_base64,omitempty"`
+}
+
+// ContentTypeStructured is the content type of MarshalStructured output.
+const ContentTypeStructured = "application/cloudevents+json"
+
+func MarshalStructured(e *Event) ([]byte, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	s := structuredEvent{
+		SpecVersion:     SpecVersion,
+		ID:              e.ID,
+		Source:          e.Source,
+		Type:            e.Type,
+		Subject:         e.Subject,
+		DataContentType: e.DataContentType,
+		Version:         e.schemaVersion(),
+		Headers:         e.Headers,
+	}
+	if !e.Time.IsZero() {
+		t := e.Time.UTC()
+		s.Time = &t
+	}
+	if isJSON(e.DataContentType) && json.Valid(e.Data) {
+		s.Data = e.Data
+	} else if len(e.Data) > 0 {
+		s.DataBase64 = e.Data
+	}
+	return json.Marshal(s)
+}
+
+func UnmarshalStructured(b []byte) (*Event, error) {
+	var s structuredEvent
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("error unmarshalling event: %w", err)
+	}
+	if s.SpecVersion == "" {
+		return nil, ErrNotCloudEvent
+	}
+	if s.SpecVersion != SpecVersion {
+		return nil, fmt.Errorf("unsupported CloudEvents specversion %q", s.SpecVersion)
+	}
+
+	e := &Event{
+		ID:              s.ID,
+		Source:          s.Source,
+		Type:            s.Type,
+		Subject:         s.Subject,
+		DataContentType: s.DataContentType,
+		SchemaVersion:   s.Version,
+		Headers:         s.Headers,
+	}
+	if s.Time != nil {
+		e.Time = *s.Time
+	}
+	switch {
+	case len(s.DataBase64) > 0:
+		e.Data = s.DataBase64
+	case len(s.Data) > 0:
+		// NOTE: a JSON string is the payload itself unless the payload is JSON
+		if s.Data[0] == '"' && !isJSON(s.DataContentType) {
+			var str string
+			if err := json.Unmarshal(s.Data, &str); err != nil {
+				return nil, fmt.Errorf("error unmarshalling data: %w", err)
+			}
+			e.Data = []byte(str)
+		} else {
+			e.Data = bytes.Clone(s.Data)
+		}
+	}
+
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(e.SchemaVersion); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,41 @@
+package shared
+
+import "time"
+
+// EventRecord is a row of events_raw.
+// Empty strings and nil pointers are stored as NULL.
+type EventRecord struct {
+	EventType   string    `json:"event_type"`
+	Id          string    `json:"id"`
+	Metadata    string    `json:"metadata"`
+	TimeCreated time.Time `json:"time_created"`
+	Signature   string    `json:"signature"`
+	MsgId       string    `json:"msg_id"`
+	Source      string    `json:"source"`
+	// NOTE: only set for push events
+	IsDefaultBranch *bool  `json:"is_default_branch,omitempty"`
+	Repository      string `json:"repository,omitempty"`
+	Organization    string `json:"organization,omitempty"`
+	Actor           string `json:"actor,omitempty"`
+	Ref             string `json:"ref,omitempty"`
+	CommitSha       string `json:"commit_sha,omitempty"`
+	Environment     string `json:"environment,omitempty"`
+}
+
+// UniqueByMsgId keeps the first record of each Pub/Sub message.
+// Sinks write at-least-once, so a redelivered message may be stored twice.
+// Records without msg_id are kept as is.
+func UniqueByMsgId(records []*EventRecord) []*EventRecord {
+	seen := make(map[string]bool, len(records))
+	unique := records[:0:0]
+	for _, r := range records {
+		if r.MsgId != "" {
+			if seen[r.MsgId] {
+				continue
+			}
+			seen[r.MsgId] = true
+		}
+		unique = append(unique, r)
+	}
+	return unique
+}
@@ -0,0 +1,23 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUniqueByMsgId(t *testing.T) {
+	records := []*EventRecord{
+		{Id: "d1", MsgId: "1"},
+		{Id: "d1", MsgId: "1"},
+		{Id: "d2", MsgId: "2"},
+		{Id: "legacy"},
+		{Id: "legacy"},
+	}
+	var ids []string
+	for _, r := range UniqueByMsgId(records) {
+		ids = append(ids, r.Id+"/"+r.MsgId)
+	}
+	if want := []string{"d1/1", "d2/2", "legacy/", "legacy/"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,213 @@
+// Package httpmw holds the HTTP middleware shared by all services.
+package httpmw
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h so that the first middleware runs first.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+type Config struct {
+	// ProjectID qualifies trace ids in logs for Cloud Logging.
+	ProjectID string
+	// MaxBodyBytes limits request bodies, 0 means no limit.
+	MaxBodyBytes int64
+	// Timeout limits the handler, 0 means no timeout.
+	Timeout time.Duration
+}
+
+// Wrap applies the standard stack: logger, request id, trace correlation,
+// access log, panic recovery, body size limit and timeout.
+func Wrap(h http.Handler, c Config) http.Handler {
+	return Chain(h,
+		Logger(),
+		RequestID(),
+		Trace(c.ProjectID),
+		AccessLog(),
+		Recover(),
+		MaxBodySize(c.MaxBodyBytes),
+		Timeout(c.Timeout),
+	)
+}
+
+// Logger puts a logger into the request context.
+func Logger() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := shared.WithLogger(r.Context())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// with adds attrs to the logger of r and returns r with the new context.
+func with(r *http.Request, attrs ...any) *http.Request {
+	ctx := r.Context()
+	logger := shared.LoggerFromContext(ctx).With(attrs...)
+	return r.WithContext(shared.SetLogger(ctx, logger))
+}
+
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// RequestID reuses the X-Request-Id of the request or generates one,
+// and echoes it in the response.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+			next.ServeHTTP(w, with(r, slog.String("requestId", id)))
+		})
+	}
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Recorder remembers the status code and size of a response.
+type Recorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func NewRecorder(w http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w}
+}
+
+func (r *Recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns 200 when nothing was written, like net/http does.
+func (r *Recorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *Recorder) Size() int64 {
+	return r.size
+}
+
+func (r *Recorder) written() bool {
+	return r.status != 0
+}
+
+// AccessLog logs every request once it completes, with its status and latency.
+func AccessLog() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := NewRecorder(w)
+			next.ServeHTTP(rec, r)
+
+			logger := shared.LoggerFromContext(r.Context())
+			level := slog.LevelInfo
+			if rec.Status() >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.Log(r.Context(), level, "request completed",
+				shared.HTTPRequest(r, rec.Status(), rec.Size(), time.Since(start)),
+			)
+		})
+	}
+}
+
+// Recover turns panics into 500 responses.
+func Recover() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := NewRecorder(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				// NOTE: lets net/http abort the response as it would without this middleware
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				logger := shared.LoggerFromContext(r.Context())
+				logger.Error(fmt.Sprintf("panic: %v", v), slog.String("stack", string(debug.Stack())))
+				if !rec.written() {
+					rec.WriteHeader(http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// MaxBodySize makes reads beyond n bytes fail. 0 disables the limit.
+func MaxBodySize(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Timeout cancels the request context and responds 503 after d. 0 disables it.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.TimeoutHandler(next, d, "request timed out")
+	}
+}
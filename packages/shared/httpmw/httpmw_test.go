@@ -0,0 +1,107 @@
+package httpmw
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+// withBuffer replaces the logger with one writing to buf.
+func withBuffer(buf *bytes.Buffer) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := shared.SetLogger(r.Context(), slog.New(slog.NewJSONHandler(buf, nil)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func TestTraceAndRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shared.LoggerFromContext(r.Context()).Info("handled")
+	}), withBuffer(&buf), RequestID(), Trace("project"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	log := buf.String()
+	for _, want := range []string{
+		`"logging.googleapis.com/trace":"projects/project/traces/105445aa7843bc8bf206b12000100000"`,
+		`"logging.googleapis.com/spanId":"0000000000000001"`,
+		`"logging.googleapis.com/trace_sampled":true`,
+		`"requestId":"` + rec.Header().Get(RequestIDHeader) + `"`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log %s does not contain %s", log, want)
+		}
+	}
+	if rec.Header().Get(RequestIDHeader) == "" {
+		t.Errorf("request id not set")
+	}
+}
+
+func TestTraceFromRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	tc, ok := TraceFromRequest(req)
+	want := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	if !ok || tc != want {
+		t.Errorf("got %+v, want %+v", tc, want)
+	}
+
+	if _, ok := TraceFromRequest(httptest.NewRequest(http.MethodGet, "/", nil)); ok {
+		t.Errorf("expected no trace")
+	}
+}
+
+func TestAccessLogAndRecover(t *testing.T) {
+	var buf bytes.Buffer
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), withBuffer(&buf), AccessLog(), Recover())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hook", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status: %d", rec.Code)
+	}
+	log := buf.String()
+	if !strings.Contains(log, `"msg":"panic: boom"`) || !strings.Contains(log, `"status":500`) || !strings.Contains(log, `"requestUrl":"/hook"`) {
+		t.Errorf("log: %s", log)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	var readErr error
+	h := MaxBodySize(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too long")))
+	if readErr == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	h := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(shared.WithLogger(context.Background()))
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status: %d", rec.Code)
+	}
+}
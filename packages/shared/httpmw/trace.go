@@ -0,0 +1,87 @@
+package httpmw
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceContext identifies the trace a request belongs to.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+var cloudTraceContext = regexp.MustCompile(`^([a-f\d]{32})(?:/(\d+))?(?:;o=([01]))?`)
+
+// TraceFromRequest prefers the OpenTelemetry span of the request context,
+// then the W3C traceparent header, then X-Cloud-Trace-Context.
+func TraceFromRequest(r *http.Request) (TraceContext, bool) {
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		return TraceContext{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Sampled: sc.IsSampled()}, true
+	}
+	if tc, ok := parseTraceparent(r.Header.Get("Traceparent")); ok {
+		return tc, true
+	}
+	return parseCloudTraceContext(r.Header.Get("X-Cloud-Trace-Context"))
+}
+
+// parseTraceparent reads "00-<trace id>-<span id>-<flags>".
+func parseTraceparent(v string) (TraceContext, bool) {
+	parts := strings.Split(v, "-")
+	if len(parts) != 4 {
+		return TraceContext{}, false
+	}
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return TraceContext{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		return TraceContext{}, false
+	}
+	return TraceContext{TraceID: traceID.String(), SpanID: spanID.String(), Sampled: strings.HasSuffix(parts[3], "1")}, true
+}
+
+// parseCloudTraceContext reads "TRACE_ID/SPAN_ID;o=OPTIONS".
+// NOTE: https://cloud.google.com/trace/docs/trace-context#legacy-http-header
+func parseCloudTraceContext(v string) (TraceContext, bool) {
+	m := cloudTraceContext.FindStringSubmatch(v)
+	if m == nil {
+		return TraceContext{}, false
+	}
+	tc := TraceContext{TraceID: m[1], Sampled: m[3] == "1"}
+	if m[2] != "" {
+		var spanID uint64
+		fmt.Sscan(m[2], &spanID)
+		tc.SpanID = fmt.Sprintf("%016x", spanID)
+	}
+	return tc, true
+}
+
+// Trace adds the trace of the request to its logger so that Cloud Logging
+// groups the logs of a request.
+func Trace(projectID string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tc, ok := TraceFromRequest(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			attrs := []any{
+				slog.String("logging.googleapis.com/trace", fmt.Sprintf("projects/%s/traces/%s", projectID, tc.TraceID)),
+				slog.Bool("logging.googleapis.com/trace_sampled", tc.Sampled),
+			}
+			if tc.SpanID != "" {
+				attrs = append(attrs, slog.String("logging.googleapis.com/spanId", tc.SpanID))
+			}
+			next.ServeHTTP(w, with(r, attrs...))
+		})
+	}
+}
@@ -2,24 +2,88 @@ package shared
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type contextKey string
 
 const loggerKey contextKey = "logger"
 
-func WithLogger(ctx context.Context) context.Context {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		//AddSource: true,
-		Level: slog.LevelInfo,
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+type LoggerConfig struct {
+	Level slog.Level
+	// Format is LogFormatJSON (default) or LogFormatText.
+	Format string
+	// AddSource adds logging.googleapis.com/sourceLocation to every entry.
+	AddSource bool
+	// Labels are added to every entry as logging.googleapis.com/labels.
+	Labels map[string]string
+}
+
+// LoggerConfigFromEnv reads LOG_LEVEL (debug, info, warn or error), LOG_FORMAT (json or text),
+// LOG_SOURCE (a bool) and LOG_LABELS ("key=value,key=value").
+func LoggerConfigFromEnv() (LoggerConfig, error) {
+	var c LoggerConfig
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := c.Level.UnmarshalText([]byte(v)); err != nil {
+			return c, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+	}
+	c.Format = os.Getenv("LOG_FORMAT")
+	if c.Format != "" && c.Format != LogFormatJSON && c.Format != LogFormatText {
+		return c, fmt.Errorf("invalid LOG_FORMAT %q", c.Format)
+	}
+	if v := os.Getenv("LOG_SOURCE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid LOG_SOURCE: %w", err)
+		}
+		c.AddSource = b
+	}
+	if v := os.Getenv("LOG_LABELS"); v != "" {
+		c.Labels = map[string]string{}
+		for _, pair := range strings.Split(v, ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok || k == "" {
+				return c, fmt.Errorf("invalid LOG_LABELS entry %q", pair)
+			}
+			c.Labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return c, nil
+}
+
+// NewLogger builds a logger whose JSON output is understood by Cloud Logging.
+func NewLogger(w io.Writer, c LoggerConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		AddSource: c.AddSource,
+		Level:     c.Level,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
 			switch a.Key {
 			case slog.LevelKey:
+				// NOTE: a "level" attr logged by the caller is not a slog.Level, keep it as is
+				level, ok := a.Value.Any().(slog.Level)
+				if !ok {
+					return a
+				}
 				a = slog.Attr{
 					Key:   "severity",
-					Value: a.Value,
+					Value: slog.StringValue(severity(level)),
 				}
 			case slog.SourceKey:
 				a = slog.Attr{
@@ -29,19 +93,87 @@ func WithLogger(ctx context.Context) context.Context {
 			}
 			return a
 		},
-	}))
+	}
 
-	return context.WithValue(ctx, loggerKey, logger)
+	var h slog.Handler
+	if c.Format == LogFormatText {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	logger := slog.New(h)
+	if len(c.Labels) > 0 {
+		labels := make([]any, 0, len(c.Labels))
+		for k, v := range c.Labels {
+			labels = append(labels, slog.String(k, v))
+		}
+		logger = logger.With(slog.Group("logging.googleapis.com/labels", labels...))
+	}
+	return logger
+}
+
+// severity maps slog levels to the Cloud Logging LogSeverity names.
+func severity(l slog.Level) string {
+	switch {
+	case l < slog.LevelInfo:
+		return "DEBUG"
+	case l < slog.LevelWarn:
+		return "INFO"
+	case l < slog.LevelError:
+		return "WARNING"
+	default:
+		return "ERROR"
+	}
+}
+
+var (
+	defaultLoggerOnce sync.Once
+	defaultLogger     *slog.Logger
+)
+
+// DefaultLogger is built once from the environment.
+// An invalid configuration falls back to the defaults, and says so.
+func DefaultLogger() *slog.Logger {
+	defaultLoggerOnce.Do(func() {
+		c, err := LoggerConfigFromEnv()
+		defaultLogger = NewLogger(os.Stdout, c)
+		if err != nil {
+			defaultLogger.Warn(fmt.Sprintf("error configuring logger, using defaults: %s", err))
+		}
+	})
+	return defaultLogger
+}
+
+func WithLogger(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loggerKey, DefaultLogger())
 }
 
 func SetLogger(ctx context.Context, logger *slog.Logger) context.Context {
 	return context.WithValue(ctx, loggerKey, logger)
 }
 
+// LoggerFromContext falls back to DefaultLogger when ctx has no logger.
 func LoggerFromContext(ctx context.Context) *slog.Logger {
 	logger, ok := ctx.Value(loggerKey).(*slog.Logger)
 	if !ok {
-		panic("logger not found in context")
+		return DefaultLogger()
 	}
 	return logger
 }
+
+// HTTPRequest formats a served request as the Cloud Logging httpRequest field.
+// NOTE: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+func HTTPRequest(r *http.Request, status int, responseSize int64, latency time.Duration) slog.Attr {
+	return slog.Group("httpRequest",
+		slog.String("requestMethod", r.Method),
+		slog.String("requestUrl", r.URL.String()),
+		slog.String("requestSize", strconv.FormatInt(r.ContentLength, 10)),
+		slog.Int("status", status),
+		slog.String("responseSize", strconv.FormatInt(responseSize, 10)),
+		slog.String("userAgent", r.UserAgent()),
+		slog.String("remoteIp", r.RemoteAddr),
+		slog.String("referer", r.Referer()),
+		slog.String("latency", fmt.Sprintf("%.9fs", latency.Seconds())),
+		slog.String("protocol", r.Proto),
+	)
+}
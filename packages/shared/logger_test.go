@@ -0,0 +1,85 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoggerConfigFromEnv(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("LOG_FORMAT", "text")
+	t.Setenv("LOG_SOURCE", "true")
+	t.Setenv("LOG_LABELS", "service=parser, env=dev")
+	c, err := LoggerConfigFromEnv()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if c.Level != slog.LevelDebug || c.Format != LogFormatText || !c.AddSource || c.Labels["env"] != "dev" {
+		t.Errorf("config: %+v", c)
+	}
+
+	t.Setenv("LOG_FORMAT", "xml")
+	if _, err := LoggerConfigFromEnv(); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LoggerConfig{Level: slog.LevelWarn, Labels: map[string]string{"service": "parser"}})
+	logger.Info("dropped")
+	logger.Warn("kept")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("error: %v, log: %s", err, buf.String())
+	}
+	if entry["severity"] != "WARNING" || entry["msg"] != "kept" {
+		t.Errorf("entry: %v", entry)
+	}
+	if labels, _ := entry["logging.googleapis.com/labels"].(map[string]any); labels["service"] != "parser" {
+		t.Errorf("labels: %v", entry["logging.googleapis.com/labels"])
+	}
+}
+
+func TestNewLoggerUserLevelAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LoggerConfig{})
+	logger.Info("escalated", slog.String("level", "L2"))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("error: %v, log: %s", err, buf.String())
+	}
+	if entry["severity"] != "INFO" || entry["level"] != "L2" {
+		t.Errorf("entry: %v", entry)
+	}
+}
+
+func TestLoggerFromContextFallback(t *testing.T) {
+	if LoggerFromContext(context.Background()) != DefaultLogger() {
+		t.Errorf("expected the default logger")
+	}
+}
+
+func TestHTTPRequest(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LoggerConfig{})
+	r := httptest.NewRequest("POST", "/hook?x=1", nil)
+	logger.Info("request completed", HTTPRequest(r, 204, 0, 1500*time.Millisecond))
+
+	var entry struct {
+		HTTPRequest map[string]any `json:"httpRequest"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if entry.HTTPRequest["requestUrl"] != "/hook?x=1" || entry.HTTPRequest["status"] != float64(204) || entry.HTTPRequest["latency"] != "1.500000000s" {
+		t.Errorf("httpRequest: %v", entry.HTTPRequest)
+	}
+}
@@ -1,59 +1,88 @@
 package shared
 
 import (
+	"encoding/json"
 	"fmt"
-	"reflect"
+	"math"
 	"strings"
 )
 
 func LookupMap[T any](m map[string]interface{}, keys ...string) (T, bool) {
-	var ok bool
-	var val interface{} = m
+	result, err := LookupMapE[T](m, keys...)
+	return result, err == nil
+}
 
-	for _, key := range keys {
-		m, ok = val.(map[string]interface{})
-		if !ok {
-			return reflect.Zero(reflect.TypeOf((*T)(nil)).Elem()).Interface().(T), false
-		}
+func LookupMapE[T any](m map[string]interface{}, keys ...string) (T, error) {
+	var zero T
 
-		val, ok = m[key]
-		if !ok {
-			return reflect.Zero(reflect.TypeOf((*T)(nil)).Elem()).Interface().(T), false
-		}
+	val, err := walk(m, keys)
+	if err != nil {
+		return zero, err
 	}
 
-	result, ok := val.(T)
+	result, ok := castNumber[T](val)
 	if !ok {
-		return reflect.Zero(reflect.TypeOf((*T)(nil)).Elem()).Interface().(T), false
+		return zero, fmt.Errorf("value %v is not of type %T", val, result)
 	}
 
-	return result, true
+	return result, nil
 }
 
-func LookupMapE[T any](m map[string]interface{}, keys ...string) (T, error) {
+func walk(m map[string]interface{}, keys []string) (interface{}, error) {
 	var ok bool
 	var val interface{} = m
 	walkedKeys := make([]string, 0, len(keys))
 
-	zero := reflect.Zero(reflect.TypeOf((*T)(nil)).Elem()).Interface().(T)
-
 	for _, key := range keys {
 		m, ok = val.(map[string]interface{})
 		if !ok {
-			return zero, fmt.Errorf("key %s is not a map: %v", strings.Join(walkedKeys, "."), m)
+			return nil, fmt.Errorf("key %s is not a map: %v", strings.Join(walkedKeys, "."), m)
 		}
 		walkedKeys = append(walkedKeys, key)
 
 		val, ok = m[key]
 		if !ok {
-			return zero, fmt.Errorf("key %s not found in %v", strings.Join(walkedKeys, "."), m)
+			return nil, fmt.Errorf("key %s not found in %v", strings.Join(walkedKeys, "."), m)
 		}
 	}
 
-	result, ok := val.(T)
-	if !ok {
-		return zero, fmt.Errorf("value %v is not of type %T", val, result)
+	return val, nil
+}
+
+// castNumber is a type assertion that also converts JSON numbers (float64 or json.Number)
+// to the requested numeric type when the value is representable without loss.
+func castNumber[T any](val interface{}) (T, bool) {
+	var zero T
+	if result, ok := val.(T); ok {
+		return result, true
 	}
 
-	return result, nil
+	var result interface{}
+	switch any(zero).(type) {
+	case int:
+		n, err := ToInt64(val)
+		if err != nil || n < math.MinInt || n > math.MaxInt {
+			return zero, false
+		}
+		result = int(n)
+	case int64:
+		n, err := ToInt64(val)
+		if err != nil {
+			return zero, false
+		}
+		result = n
+	case float64:
+		n, ok := val.(json.Number)
+		if !ok {
+			return zero, false
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return zero, false
+		}
+		result = f
+	default:
+		return zero, false
+	}
+	return result.(T), true
 }
@@ -0,0 +1,148 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultKeyTTL  = time.Hour
+	minRefreshWait = time.Minute
+)
+
+type keySource interface {
+	key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+type staticKeySet map[string]*rsa.PublicKey
+
+func (s staticKeySet) key(_ context.Context, kid string) (*rsa.PublicKey, error) {
+	k, ok := s[kid]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found", kid)
+	}
+	return k, nil
+}
+
+func loadKeyFile(path string) (staticKeySet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading jwks %s: %w", path, err)
+	}
+	return parseKeySet(b)
+}
+
+func parseKeySet(b []byte) (staticKeySet, error) {
+	var set jwks
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("error unmarshalling jwks: %w", err)
+	}
+
+	keys := make(staticKeySet, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid exponent: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// remoteKeySet caches a JWKS document for its Cache-Control max-age and
+// refetches it early when an unknown kid shows up (keys are rotated).
+type remoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      staticKeySet
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+func (r *remoteKeySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	_, known := r.keys[kid]
+	if now.After(r.expiresAt) || (!known && now.Sub(r.fetchedAt) > minRefreshWait) {
+		if err := r.fetch(ctx, now); err != nil {
+			return nil, err
+		}
+	}
+	return r.keys.key(ctx, kid)
+}
+
+func (r *remoteKeySet) fetch(ctx context.Context, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching jwks: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching jwks: status %d", res.StatusCode)
+	}
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading jwks: %w", err)
+	}
+	keys, err := parseKeySet(b)
+	if err != nil {
+		return err
+	}
+
+	r.keys = keys
+	r.fetchedAt = now
+	r.expiresAt = now.Add(maxAge(res.Header.Get("Cache-Control")))
+	return nil
+}
+
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		v, ok := strings.CutPrefix(strings.TrimSpace(directive), "max-age=")
+		if !ok {
+			continue
+		}
+		if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+			return time.Duration(sec) * time.Second
+		}
+	}
+	return defaultKeyTTL
+}
@@ -0,0 +1,184 @@
+// Package oidc verifies the OIDC tokens Pub/Sub attaches to push requests.
+//
+// See https://cloud.google.com/pubsub/docs/authenticate-push-subscriptions
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	GoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+	leeway = time.Minute
+)
+
+var GoogleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+type Config struct {
+	// Issuers accepted in the iss claim. Defaults to GoogleIssuers.
+	Issuers []string
+	// Email is the service account the push subscription signs tokens as. Required,
+	// otherwise any Google identity could get a token for the audience.
+	Email string
+	// JWKSURL is fetched and cached when JWKSFile is empty. Defaults to GoogleJWKSURL.
+	JWKSURL string
+	// JWKSFile is a local JWKS document, mainly for testing.
+	JWKSFile   string
+	HTTPClient *http.Client
+}
+
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Audience      audience `json:"aud"`
+	Subject       string   `json:"sub"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+}
+
+// audience accepts both the string and the array form of the aud claim.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+type Verifier struct {
+	issuers []string
+	email   string
+	keys    keySource
+	now     func() time.Time
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.Email == "" {
+		return nil, errors.New("service account email is required")
+	}
+	v := &Verifier{
+		issuers: cfg.Issuers,
+		email:   cfg.Email,
+		now:     time.Now,
+	}
+	if len(v.issuers) == 0 {
+		v.issuers = GoogleIssuers
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadKeyFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+	} else {
+		url := cfg.JWKSURL
+		if url == "" {
+			url = GoogleJWKSURL
+		}
+		client := cfg.HTTPClient
+		if client == nil {
+			client = http.DefaultClient
+		}
+		v.keys = &remoteKeySet{url: url, client: client}
+	}
+
+	return v, nil
+}
+
+// BearerToken extracts the token of an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, error) {
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		return "", errors.New("authorization header not found")
+	}
+	token, ok := strings.CutPrefix(h, "Bearer ")
+	if !ok || token == "" {
+		return "", errors.New("authorization header is not a bearer token")
+	}
+	return token, nil
+}
+
+// Verify checks the signature, issuer, audience, expiry and email of rawToken.
+func (v *Verifier) Verify(ctx context.Context, rawToken string, aud string) (*Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported algorithm %s", header.Alg)
+	}
+
+	key, err := v.keys.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+
+	now := v.now()
+	switch {
+	case !slices.Contains(v.issuers, claims.Issuer):
+		return nil, fmt.Errorf("unexpected issuer %s", claims.Issuer)
+	case !slices.Contains(claims.Audience, aud):
+		return nil, fmt.Errorf("unexpected audience %v", []string(claims.Audience))
+	case now.After(time.Unix(claims.ExpiresAt, 0).Add(leeway)):
+		return nil, errors.New("token expired")
+	case now.Add(leeway).Before(time.Unix(claims.IssuedAt, 0)):
+		return nil, errors.New("token used before issued")
+	case !claims.EmailVerified:
+		return nil, errors.New("email not verified")
+	case claims.Email != v.email:
+		return nil, fmt.Errorf("unexpected email %s", claims.Email)
+	}
+
+	return &claims, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
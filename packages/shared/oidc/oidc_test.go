@@ -0,0 +1,133 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	jwksPath := writeJWKS(t, "test-kid", &key.PublicKey)
+
+	v, err := NewVerifier(Config{JWKSFile: jwksPath, Email: "fourkeys@example.iam.gserviceaccount.com"})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	v.now = func() time.Time { return now }
+
+	valid := map[string]interface{}{
+		"iss":            "https://accounts.google.com",
+		"aud":            "https://parser.example.com",
+		"email":          "fourkeys@example.iam.gserviceaccount.com",
+		"email_verified": true,
+		"iat":            now.Add(-time.Minute).Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+	with := func(k string, val interface{}) map[string]interface{} {
+		c := map[string]interface{}{}
+		for k, v := range valid {
+			c[k] = v
+		}
+		c[k] = val
+		return c
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		claims, err := v.Verify(context.Background(), sign(t, key, "test-kid", valid), "https://parser.example.com")
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if claims.Email != "fourkeys@example.iam.gserviceaccount.com" {
+			t.Errorf("email: %v", claims.Email)
+		}
+	})
+
+	failures := []struct {
+		name  string
+		token string
+	}{
+		{"issuer", sign(t, key, "test-kid", with("iss", "https://evil.example.com"))},
+		{"audience", sign(t, key, "test-kid", with("aud", "https://other.example.com"))},
+		{"expired", sign(t, key, "test-kid", with("exp", now.Add(-time.Hour).Unix()))},
+		{"email", sign(t, key, "test-kid", with("email", "other@example.com"))},
+		{"email not verified", sign(t, key, "test-kid", with("email_verified", false))},
+		{"unknown kid", sign(t, key, "other-kid", valid)},
+		{"malformed", "not-a-token"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(context.Background(), tt.token, "https://parser.example.com"); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+
+	t.Run("signature", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if _, err := v.Verify(context.Background(), sign(t, other, "test-kid", valid), "https://parser.example.com"); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func writeJWKS(t *testing.T, kid string, pub *rsa.PublicKey) string {
+	t.Helper()
+	b, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return path
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signingInput := encode(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestNewVerifierRequiresEmail(t *testing.T) {
+	if _, err := NewVerifier(Config{JWKSURL: GoogleJWKSURL}); err == nil {
+		t.Errorf("expected error without email")
+	}
+}
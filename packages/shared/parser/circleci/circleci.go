@@ -0,0 +1,77 @@
+package circleci
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/mapping"
+)
+
+//go:embed events.json
+var defaultEvents []byte
+
+type Config struct {
+	// Events adds or replaces the mapping rules of events.json by event type.
+	Events mapping.Rules `json:"events"`
+}
+
+type Parser struct {
+	events mapping.Rules
+}
+
+func New(config Config) (*Parser, error) {
+	events, err := mapping.Load(defaultEvents, config.Events)
+	if err != nil {
+		return nil, fmt.Errorf("invalid events config: %w", err)
+	}
+	return &Parser{events: events}, nil
+}
+
+func (p *Parser) Source() string {
+	return "circleci"
+}
+
+func (p *Parser) Parse(ctx context.Context, headers map[string][]string, body []byte) (*shared.EventRecord, error) {
+	logger := shared.LoggerFromContext(ctx)
+
+	eventType := parser.Header(headers, "Circleci-Event-Type")
+	rule, ok := p.events.Lookup(eventType)
+	if !ok {
+		logger.Warn(fmt.Sprintf("event type %s is not supported", eventType))
+		parser.RecordSkip(ctx, eventType, parser.SkipUnsupported)
+		return nil, nil
+	}
+
+	metadata, err := shared.DecodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling data: %w", err)
+	}
+	if reason := rule.FilterReason(metadata); reason != "" {
+		logger.Info(fmt.Sprintf("%s event skipped: %s", eventType, reason))
+		parser.RecordSkip(ctx, eventType, parser.SkipFiltered)
+		return nil, nil
+	}
+
+	id, iErr := rule.EventID(metadata)
+	maybeTimeCreated, tErr := rule.TimeCreated(metadata)
+	if err := errors.Join(iErr, tErr); err != nil {
+		return nil, err
+	}
+	timeCreated, err := shared.ParseTimestamp(maybeTimeCreated)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse time_created: %w", err)
+	}
+
+	return &shared.EventRecord{
+		EventType:   eventType,
+		Id:          id,
+		Metadata:    string(body),
+		TimeCreated: timeCreated,
+		Signature:   parser.Header(headers, "Circleci-Signature"),
+		Source:      "circleci",
+	}, nil
+}
@@ -0,0 +1,41 @@
+package circleci
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func TestParse(t *testing.T) {
+	ctx := shared.WithLogger(context.Background())
+	p, err := New(Config{})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	t.Run("workflow completed", func(t *testing.T) {
+		body := []byte(`{"id":"3888f21b-eaa7-38e3-8f3d-75a63bba8895","happened_at":"2021-09-01T22:49:34.317Z","workflow":{"name":"deploy","status":"success"}}`)
+		e, err := p.Parse(ctx, map[string][]string{
+			"Circleci-Event-Type": {"workflow-completed"},
+			"Circleci-Signature":  {"v1=abc"},
+		}, body)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "3888f21b-eaa7-38e3-8f3d-75a63bba8895" || e.EventType != "workflow-completed" || e.Source != "circleci" || e.Signature != "v1=abc" {
+			t.Errorf("record: %+v", e)
+		}
+		if want := time.Date(2021, 9, 1, 22, 49, 34, 317000000, time.UTC); !e.TimeCreated.Equal(want) {
+			t.Errorf("time_created: %v, want %v", e.TimeCreated, want)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		e, err := p.Parse(ctx, map[string][]string{"Circleci-Event-Type": {"ping"}}, []byte(`{}`))
+		if err != nil || e != nil {
+			t.Errorf("expected skip, got %+v, %v", e, err)
+		}
+	})
+}
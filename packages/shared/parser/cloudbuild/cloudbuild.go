@@ -0,0 +1,65 @@
+package cloudbuild
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+)
+
+type Parser struct{}
+
+func New() *Parser {
+	return &Parser{}
+}
+
+func (p *Parser) Source() string {
+	return "cloud_build"
+}
+
+// Parse handles the build notifications Cloud Build publishes to the cloud-builds topic.
+// The message attributes (buildId, status) are passed as headers.
+func (p *Parser) Parse(_ context.Context, headers map[string][]string, body []byte) (*shared.EventRecord, error) {
+	metadata, err := shared.DecodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling data: %w", err)
+	}
+
+	id := firstValue(headers["buildId"])
+	if id == "" {
+		var ok bool
+		if id, ok = shared.LookupMap[string](metadata, "id"); !ok {
+			return nil, fmt.Errorf("could not find id")
+		}
+	}
+
+	maybeTimeCreated, ok := parser.LookupFirst(metadata,
+		[]string{"finishTime"},
+		[]string{"startTime"},
+		[]string{"createTime"},
+	)
+	if !ok {
+		return nil, fmt.Errorf("could not find time_created")
+	}
+	timeCreated, err := shared.ParseTimestamp(maybeTimeCreated)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse time_created: %w", err)
+	}
+
+	return &shared.EventRecord{
+		EventType:   "build",
+		Id:          id,
+		Metadata:    string(body),
+		TimeCreated: timeCreated,
+		Signature:   parser.UniqueID(body),
+		Source:      "cloud_build",
+	}, nil
+}
+
+func firstValue(v []string) string {
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
@@ -0,0 +1,36 @@
+package cloudbuild
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func TestParse(t *testing.T) {
+	ctx := shared.WithLogger(context.Background())
+	p := New()
+
+	t.Run("build", func(t *testing.T) {
+		body := []byte(`{"id":"ignored","status":"SUCCESS","createTime":"2022-01-05T04:00:00Z","finishTime":"2022-01-05T04:36:28Z"}`)
+		e, err := p.Parse(ctx, map[string][]string{"buildId": {"b1"}, "status": {"SUCCESS"}}, body)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "b1" || e.EventType != "build" || e.Source != "cloud_build" {
+			t.Errorf("record: %+v", e)
+		}
+		if want := time.Date(2022, 1, 5, 4, 36, 28, 0, time.UTC); !e.TimeCreated.Equal(want) {
+			t.Errorf("time_created: %v, want %v", e.TimeCreated, want)
+		}
+	})
+
+	// NOTE: every Cloud Build notification is recorded, malformed ones are errors instead
+	t.Run("no id", func(t *testing.T) {
+		e, err := p.Parse(ctx, nil, []byte(`{"createTime":"2022-01-05T04:00:00Z"}`))
+		if err == nil {
+			t.Errorf("expected error, got %+v", e)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DetectSource guesses the webhook source from its headers.
+// Unknown sources fall back to the User-Agent.
+func DetectSource(headers map[string][]string) string {
+	header := http.Header(headers)
+	if _, ok := header["X-Gitlab-Event"]; ok {
+		return "gitlab"
+	}
+	if _, ok := header["Ce-Type"]; ok {
+		if v := header.Get("Ce-Type"); strings.Contains(v, "tekton") {
+			return "tekton"
+		}
+	}
+	if _, ok := header["User-Agent"]; ok {
+		if v := header.Get("User-Agent"); strings.Contains(v, "GitHub-Hookshot") {
+			return "github"
+		}
+	}
+	if _, ok := header["Circleci-Event-Type"]; ok {
+		return "circleci"
+	}
+	if _, ok := header["X-Pagerduty-Signature"]; ok {
+		return "pagerduty"
+	}
+	// NOTE: Cloud Build publishes to Pub/Sub itself, its message attributes are used as headers
+	if _, ok := headers["buildId"]; ok {
+		return "cloud_build"
+	}
+
+	return header.Get("User-Agent")
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type branchMode string
+
+const (
+	// branchModeTag stores every push and records whether its ref matched the rules.
+	branchModeTag branchMode = "tag"
+	// branchModeDrop discards pushes whose ref does not match the rules.
+	branchModeDrop branchMode = "drop"
+)
+
+const regexPatternPrefix = "regex:"
+
+// BranchRules decides which pushes count as changes.
+// Patterns are globs (path.Match) or regular expressions prefixed with "regex:",
+// matched against both the full ref ("refs/heads/main") and the branch name ("main").
+// When no pattern applies to a repository, its default branch is used.
+type BranchRules struct {
+	Mode         branchMode          `json:"mode"`
+	Include      []string            `json:"include"`
+	Repositories map[string][]string `json:"repositories"`
+
+	include      []branchPattern
+	repositories map[string][]branchPattern
+}
+
+type branchPattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func (r *BranchRules) compile() error {
+	switch r.Mode {
+	case "":
+		r.Mode = branchModeTag
+	case branchModeTag, branchModeDrop:
+	default:
+		return fmt.Errorf("unknown mode %q", r.Mode)
+	}
+
+	include, err := compileBranchPatterns(r.Include)
+	if err != nil {
+		return err
+	}
+	r.include = include
+
+	r.repositories = make(map[string][]branchPattern, len(r.Repositories))
+	for repo, raw := range r.Repositories {
+		patterns, err := compileBranchPatterns(raw)
+		if err != nil {
+			return fmt.Errorf("repository %s: %w", repo, err)
+		}
+		r.repositories[repo] = patterns
+	}
+
+	return nil
+}
+
+func compileBranchPatterns(raw []string) ([]branchPattern, error) {
+	patterns := make([]branchPattern, 0, len(raw))
+	for _, p := range raw {
+		if expr, ok := strings.CutPrefix(p, regexPatternPrefix); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+			patterns = append(patterns, branchPattern{re: re})
+			continue
+		}
+
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, branchPattern{glob: p})
+	}
+	return patterns, nil
+}
+
+func (p branchPattern) match(ref string) bool {
+	name := strings.TrimPrefix(ref, "refs/heads/")
+	if p.re != nil {
+		return p.re.MatchString(ref) || p.re.MatchString(name)
+	}
+
+	for _, s := range []string{ref, name} {
+		if ok, _ := path.Match(p.glob, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *BranchRules) match(repository, defaultBranch, ref string) bool {
+	patterns, ok := r.repositories[repository]
+	if !ok {
+		patterns = r.include
+	}
+
+	if len(patterns) == 0 {
+		return defaultBranch != "" && ref == "refs/heads/"+defaultBranch
+	}
+
+	for _, p := range patterns {
+		if p.match(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *BranchRules) matchPush(metadata map[string]interface{}) bool {
+	ref, _ := shared.LookupMap[string](metadata, "ref")
+	repository, _ := shared.LookupMap[string](metadata, "repository", "full_name")
+	defaultBranch, _ := shared.LookupMap[string](metadata, "repository", "default_branch")
+
+	return r.match(repository, defaultBranch, ref)
+}
@@ -0,0 +1,57 @@
+package github
+
+import "testing"
+
+func TestBranchRulesMatch(t *testing.T) {
+	rules := BranchRules{
+		Include: []string{"refs/heads/main", "release/*", "regex:^hotfix-[0-9]+$"},
+		Repositories: map[string][]string{
+			"org/legacy": {"master"},
+		},
+	}
+	if err := rules.compile(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		repository string
+		ref        string
+		want       bool
+	}{
+		{"full ref", "org/app", "refs/heads/main", true},
+		{"glob on branch name", "org/app", "refs/heads/release/1.0", true},
+		{"glob does not cross slash", "org/app", "refs/heads/release/1.0/rc", false},
+		{"regex", "org/app", "refs/heads/hotfix-12", true},
+		{"feature branch", "org/app", "refs/heads/feature/foo", false},
+		{"repository override", "org/legacy", "refs/heads/master", true},
+		{"repository override ignores include", "org/legacy", "refs/heads/main", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.match(tt.repository, "main", tt.ref); got != tt.want {
+				t.Errorf("match(%s, %s) = %v, want %v", tt.repository, tt.ref, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("default branch without patterns", func(t *testing.T) {
+		var empty BranchRules
+		if err := empty.compile(); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if !empty.match("org/app", "develop", "refs/heads/develop") {
+			t.Errorf("default branch should match")
+		}
+		if empty.match("org/app", "develop", "refs/heads/main") {
+			t.Errorf("non default branch should not match")
+		}
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		r := BranchRules{Mode: "ignore"}
+		if err := r.compile(); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package github
+
+import (
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+)
+
+type promotedFields struct {
+	repository   string
+	organization string
+	actor        string
+	ref          string
+	commitSha    string
+	environment  string
+}
+
+var (
+	refPaths = map[string][][]string{
+		"push":                        {{"ref"}},
+		"pull_request":                {{"pull_request", "base", "ref"}},
+		"pull_request_review":         {{"pull_request", "base", "ref"}},
+		"pull_request_review_comment": {{"pull_request", "base", "ref"}},
+		"check_run":                   {{"check_run", "check_suite", "head_branch"}},
+		"check_suite":                 {{"check_suite", "head_branch"}},
+		"deployment_status":           {{"deployment", "ref"}},
+		"release":                     {{"release", "target_commitish"}},
+	}
+	commitShaPaths = map[string][][]string{
+		"push":                        {{"after"}, {"head_commit", "id"}},
+		"pull_request":                {{"pull_request", "merge_commit_sha"}, {"pull_request", "head", "sha"}},
+		"pull_request_review":         {{"pull_request", "head", "sha"}},
+		"pull_request_review_comment": {{"comment", "commit_id"}, {"pull_request", "head", "sha"}},
+		"check_run":                   {{"check_run", "head_sha"}},
+		"check_suite":                 {{"check_suite", "head_sha"}},
+		"deployment_status":           {{"deployment", "sha"}},
+		"status":                      {{"sha"}},
+	}
+	environmentPaths = map[string][][]string{
+		"deployment_status": {{"deployment_status", "environment"}, {"deployment", "environment"}},
+	}
+)
+
+func extractPromotedFields(eventType string, metadata map[string]interface{}) promotedFields {
+	var f promotedFields
+	f.repository, _ = parser.LookupFirst(metadata, []string{"repository", "full_name"})
+	f.organization, _ = parser.LookupFirst(metadata, []string{"organization", "login"}, []string{"repository", "owner", "login"})
+	f.actor, _ = parser.LookupFirst(metadata, []string{"sender", "login"})
+	f.ref, _ = parser.LookupFirst(metadata, refPaths[eventType]...)
+	f.commitSha, _ = parser.LookupFirst(metadata, commitShaPaths[eventType]...)
+	f.environment, _ = parser.LookupFirst(metadata, environmentPaths[eventType]...)
+	return f
+}
@@ -0,0 +1,71 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func TestExtractPromotedFields(t *testing.T) {
+	const repository = `"repository":{"full_name":"org/app","owner":{"login":"org"}},"sender":{"login":"alice"}`
+	tests := []struct {
+		name      string
+		eventType string
+		body      string
+		want      promotedFields
+	}{
+		{
+			name:      "push",
+			eventType: "push",
+			body:      `{"ref":"refs/heads/main","after":"abc","head_commit":{"id":"def"},` + repository + `}`,
+			want:      promotedFields{repository: "org/app", organization: "org", actor: "alice", ref: "refs/heads/main", commitSha: "abc"},
+		},
+		{
+			name:      "push falls back to head_commit",
+			eventType: "push",
+			body:      `{"ref":"refs/heads/main","head_commit":{"id":"def"},` + repository + `}`,
+			want:      promotedFields{repository: "org/app", organization: "org", actor: "alice", ref: "refs/heads/main", commitSha: "def"},
+		},
+		{
+			name:      "pull_request",
+			eventType: "pull_request",
+			body:      `{"organization":{"login":"org-login"},"pull_request":{"base":{"ref":"main"},"merge_commit_sha":"merged","head":{"sha":"head"}},` + repository + `}`,
+			want:      promotedFields{repository: "org/app", organization: "org-login", actor: "alice", ref: "main", commitSha: "merged"},
+		},
+		{
+			name:      "pull_request without merge commit",
+			eventType: "pull_request",
+			body:      `{"pull_request":{"base":{"ref":"main"},"merge_commit_sha":null,"head":{"sha":"head"}},` + repository + `}`,
+			want:      promotedFields{repository: "org/app", organization: "org", actor: "alice", ref: "main", commitSha: "head"},
+		},
+		{
+			name:      "deployment_status",
+			eventType: "deployment_status",
+			body:      `{"deployment":{"ref":"v1.0.0","sha":"abc","environment":"staging"},"deployment_status":{"environment":"production"},` + repository + `}`,
+			want:      promotedFields{repository: "org/app", organization: "org", actor: "alice", ref: "v1.0.0", commitSha: "abc", environment: "production"},
+		},
+		{
+			name:      "check_run",
+			eventType: "check_run",
+			body:      `{"check_run":{"head_sha":"abc","check_suite":{"head_branch":"main"}},` + repository + `}`,
+			want:      promotedFields{repository: "org/app", organization: "org", actor: "alice", ref: "main", commitSha: "abc"},
+		},
+		{
+			name:      "no repository",
+			eventType: "check_run",
+			body:      `{"check_run":{}}`,
+			want:      promotedFields{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := shared.DecodeJSON([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if got := extractPromotedFields(tt.eventType, metadata); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package github
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/mapping"
+)
+
+//go:embed events.json
+var defaultEvents []byte
+
+type Config struct {
+	Branches     BranchRules      `json:"branches"`
+	Repositories RepositoryFilter `json:"repositories"`
+	// Events adds or replaces the mapping rules of events.json by event type.
+	Events mapping.Rules `json:"events"`
+}
+
+type Parser struct {
+	config   Config
+	events   mapping.Rules
+	filtered *filterCounter
+}
+
+func New(config Config) (*Parser, error) {
+	if err := config.Branches.compile(); err != nil {
+		return nil, fmt.Errorf("invalid branches config: %w", err)
+	}
+	if err := config.Repositories.validate(); err != nil {
+		return nil, fmt.Errorf("invalid repositories config: %w", err)
+	}
+	events, err := mapping.Load(defaultEvents, config.Events)
+	if err != nil {
+		return nil, fmt.Errorf("invalid events config: %w", err)
+	}
+	return &Parser{
+		config:   config,
+		events:   events,
+		filtered: &filterCounter{counts: map[string]int64{}},
+	}, nil
+}
+
+func (p *Parser) Source() string {
+	return "github"
+}
+
+func (p *Parser) Parse(ctx context.Context, headers map[string][]string, body []byte) (*shared.EventRecord, error) {
+	logger := shared.LoggerFromContext(ctx)
+
+	metadata, err := shared.DecodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling data: %w", err)
+	}
+
+	if len(headers["X-Github-Event"]) == 0 {
+		return nil, fmt.Errorf("header X-Github-Event not found")
+	}
+	eventType := headers["X-Github-Event"][0]
+	rule, ok := p.events.Lookup(eventType)
+	if !ok {
+		logger.Warn(fmt.Sprintf("event type %s is not supported", eventType))
+		parser.RecordSkip(ctx, eventType, parser.SkipUnsupported)
+		return nil, nil
+	}
+
+	var signature string
+	if v := headers["X-Hub-Signature-256"]; len(v) > 0 {
+		signature = v[0]
+	}
+	var source string
+	if _, ok := headers["Mock"]; ok {
+		source = "github_mock"
+	} else {
+		source = "github"
+	}
+
+	if reason := p.config.Repositories.filterReason(metadata); reason != "" {
+		repository, _ := shared.LookupMap[string](metadata, "repository", "full_name")
+		logger.Info("event filtered",
+			slog.String("eventType", eventType),
+			slog.String("repository", repository),
+			slog.String("reason", reason),
+			slog.Int64("filteredTotal", p.filtered.inc(reason)),
+		)
+		parser.RecordSkip(ctx, eventType, parser.SkipFiltered)
+		return nil, nil
+	}
+
+	var isDefaultBranch *bool
+	if eventType == "push" {
+		matched := p.config.Branches.matchPush(metadata)
+		if !matched && p.config.Branches.Mode == branchModeDrop {
+			ref, _ := shared.LookupMap[string](metadata, "ref")
+			logger.Info(fmt.Sprintf("push to %s does not match branch rules, skipped", ref))
+			parser.RecordSkip(ctx, eventType, parser.SkipFiltered)
+			return nil, nil
+		}
+		isDefaultBranch = &matched
+	}
+
+	if reason := rule.FilterReason(metadata); reason != "" {
+		logger.Info(fmt.Sprintf("%s event skipped: %s", eventType, reason))
+		parser.RecordSkip(ctx, eventType, parser.SkipFiltered)
+		return nil, nil
+	}
+
+	id, iErr := rule.EventID(metadata)
+	maybeTimeCreated, tErr := rule.TimeCreated(metadata)
+	if err := errors.Join(tErr, iErr); err != nil {
+		return nil, err
+	}
+
+	timeCreated, err := shared.ParseTimestamp(maybeTimeCreated)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse time_created: %w", err)
+	}
+
+	fields := extractPromotedFields(eventType, metadata)
+	return &shared.EventRecord{
+		EventType:   eventType,
+		Id:          id,
+		Metadata:    string(body),
+		TimeCreated: timeCreated,
+		Signature:   signature,
+		Source:      source,
+
+		IsDefaultBranch: isDefaultBranch,
+		Repository:      fields.repository,
+		Organization:    fields.organization,
+		Actor:           fields.actor,
+		Ref:             fields.ref,
+		CommitSha:       fields.commitSha,
+		Environment:     fields.environment,
+	}, nil
+}
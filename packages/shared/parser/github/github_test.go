@@ -0,0 +1,109 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/mapping"
+)
+
+func TestParseEvents(t *testing.T) {
+	ctx := shared.WithLogger(context.Background())
+	p, err := New(Config{
+		Events: mapping.Rules{
+			"merge_group": {
+				ID:        []string{"merge_group.head_sha"},
+				Timestamp: []string{"merge_group.head_commit.timestamp"},
+				Filters:   []mapping.Filter{{Path: "action", In: []string{"checks_requested"}}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	parse := func(eventType, body string) (*shared.EventRecord, error) {
+		return p.Parse(ctx, map[string][]string{"X-Github-Event": {eventType}}, []byte(body))
+	}
+
+	t.Run("pull_request", func(t *testing.T) {
+		e, err := parse("pull_request", `{"number":12,"repository":{"name":"repo","full_name":"org/repo"},"pull_request":{"updated_at":"2024-01-01T00:00:00Z"}}`)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "repo/12" || e.EventType != "pull_request" {
+			t.Errorf("record: %+v", e)
+		}
+	})
+
+	t.Run("deployment_status promotes fields", func(t *testing.T) {
+		e, err := parse("deployment_status", `{
+			"deployment_status":{"id":1,"state":"success","updated_at":"2024-01-01T00:00:00Z","environment":"production"},
+			"deployment":{"ref":"main","sha":"abc"},
+			"repository":{"full_name":"org/app","owner":{"login":"org"}},
+			"sender":{"login":"alice"}}`)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Repository != "org/app" || e.Organization != "org" || e.Actor != "alice" ||
+			e.Ref != "main" || e.CommitSha != "abc" || e.Environment != "production" {
+			t.Errorf("record: %+v", e)
+		}
+	})
+
+	t.Run("check_run falls back to started_at", func(t *testing.T) {
+		e, err := parse("check_run", `{"check_run":{"id":123,"started_at":"2024-01-01T00:00:00Z"}}`)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "123" || e.TimeCreated.IsZero() {
+			t.Errorf("record: %+v", e)
+		}
+	})
+
+	t.Run("large numeric id keeps precision", func(t *testing.T) {
+		e, err := parse("release", `{"release":{"id":9007199254740993,"published_at":"2024-01-01T00:00:00Z"}}`)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "9007199254740993" {
+			t.Errorf("id: %s", e.Id)
+		}
+	})
+
+	t.Run("configured merge_group", func(t *testing.T) {
+		e, err := parse("merge_group", `{"action":"checks_requested","merge_group":{"head_sha":"abc","head_commit":{"timestamp":"2024-01-01T00:00:00Z"}}}`)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "abc" {
+			t.Errorf("record: %+v", e)
+		}
+
+		e, err = parse("merge_group", `{"action":"destroyed","merge_group":{"head_sha":"abc"}}`)
+		if err != nil || e != nil {
+			t.Errorf("expected filtered, got %+v, %v", e, err)
+		}
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		if _, err := parse("release", `{"release":{}}`); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		e, err := parse("fork", `{}`)
+		if err != nil || e != nil {
+			t.Errorf("expected skip, got %+v, %v", e, err)
+		}
+
+		var skip parser.Skip
+		p.Parse(parser.WithSkip(ctx, &skip), map[string][]string{"X-Github-Event": {"fork"}}, []byte(`{}`))
+		if skip != (parser.Skip{EventType: "fork", Reason: parser.SkipUnsupported}) {
+			t.Errorf("skip: %+v", skip)
+		}
+	})
+}
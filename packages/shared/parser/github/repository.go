@@ -0,0 +1,128 @@
+package github
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+const topicPatternPrefix = "topic:"
+
+// RepositoryFilter decides which repositories are recorded.
+// Allow and Deny entries are globs on the repository full name ("org/repo")
+// or "topic:<name>" to match a repository topic. Deny wins over Allow, and
+// an empty Allow accepts every repository that is not denied.
+type RepositoryFilter struct {
+	Organizations   []string `json:"organizations"`
+	Allow           []string `json:"allow"`
+	Deny            []string `json:"deny"`
+	ExcludeForks    bool     `json:"exclude_forks"`
+	ExcludeArchived bool     `json:"exclude_archived"`
+}
+
+type repositoryInfo struct {
+	fullName     string
+	organization string
+	topics       []string
+	fork         bool
+	archived     bool
+}
+
+func repositoryInfoFromMetadata(metadata map[string]interface{}) (repositoryInfo, bool) {
+	var info repositoryInfo
+	fullName, ok := shared.LookupMap[string](metadata, "repository", "full_name")
+	if !ok {
+		return info, false
+	}
+	info.fullName = fullName
+
+	if org, ok := shared.LookupMap[string](metadata, "organization", "login"); ok {
+		info.organization = org
+	} else {
+		info.organization, _ = shared.LookupMap[string](metadata, "repository", "owner", "login")
+	}
+	info.fork, _ = shared.LookupMap[bool](metadata, "repository", "fork")
+	info.archived, _ = shared.LookupMap[bool](metadata, "repository", "archived")
+	if topics, ok := shared.LookupMap[[]interface{}](metadata, "repository", "topics"); ok {
+		for _, t := range topics {
+			if s, ok := t.(string); ok {
+				info.topics = append(info.topics, s)
+			}
+		}
+	}
+
+	return info, true
+}
+
+func (f *RepositoryFilter) validate() error {
+	for _, patterns := range [][]string{f.Allow, f.Deny} {
+		for _, p := range patterns {
+			if strings.HasPrefix(p, topicPatternPrefix) {
+				continue
+			}
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// filterReason returns why an event must be dropped, or "" when it is accepted.
+func (f *RepositoryFilter) filterReason(metadata map[string]interface{}) string {
+	info, ok := repositoryInfoFromMetadata(metadata)
+	if !ok {
+		// NOTE: organization level events (e.g. projects_v2_item) have no repository
+		org, _ := shared.LookupMap[string](metadata, "organization", "login")
+		if len(f.Organizations) > 0 && org != "" && !slices.Contains(f.Organizations, org) {
+			return "organization"
+		}
+		return ""
+	}
+
+	switch {
+	case len(f.Organizations) > 0 && !slices.Contains(f.Organizations, info.organization):
+		return "organization"
+	case f.ExcludeForks && info.fork:
+		return "fork"
+	case f.ExcludeArchived && info.archived:
+		return "archived"
+	case matchRepository(f.Deny, info):
+		return "deny"
+	case len(f.Allow) > 0 && !matchRepository(f.Allow, info):
+		return "not_allowed"
+	}
+	return ""
+}
+
+func matchRepository(patterns []string, info repositoryInfo) bool {
+	for _, p := range patterns {
+		if topic, ok := strings.CutPrefix(p, topicPatternPrefix); ok {
+			if slices.Contains(info.topics, topic) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, info.fullName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filterCounter counts filtered events by reason.
+type filterCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *filterCounter) inc(reason string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[reason]++
+	return c.counts[reason]
+}
@@ -0,0 +1,52 @@
+package github
+
+import "testing"
+
+func TestRepositoryFilterReason(t *testing.T) {
+	filter := RepositoryFilter{
+		Organizations:   []string{"org"},
+		Allow:           []string{"org/api-*", "topic:dora"},
+		Deny:            []string{"org/api-sandbox"},
+		ExcludeForks:    true,
+		ExcludeArchived: true,
+	}
+	if err := filter.validate(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	repository := func(fullName string, extra map[string]interface{}) map[string]interface{} {
+		repo := map[string]interface{}{
+			"full_name": fullName,
+			"owner":     map[string]interface{}{"login": "org"},
+		}
+		for k, v := range extra {
+			repo[k] = v
+		}
+		return map[string]interface{}{"repository": repo}
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		want     string
+	}{
+		{"allowed by glob", repository("org/api-users", nil), ""},
+		{"allowed by topic", repository("org/web", map[string]interface{}{"topics": []interface{}{"dora"}}), ""},
+		{"not allowed", repository("org/web", nil), "not_allowed"},
+		{"denied", repository("org/api-sandbox", nil), "deny"},
+		{"fork", repository("org/api-users", map[string]interface{}{"fork": true}), "fork"},
+		{"archived", repository("org/api-users", map[string]interface{}{"archived": true}), "archived"},
+		{"other organization", map[string]interface{}{
+			"repository":   map[string]interface{}{"full_name": "other/api-users"},
+			"organization": map[string]interface{}{"login": "other"},
+		}, "organization"},
+		{"no repository", map[string]interface{}{"organization": map[string]interface{}{"login": "org"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.filterReason(tt.metadata); got != tt.want {
+				t.Errorf("filterReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package gitlab
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/mapping"
+)
+
+// NOTE: a push carries its commits oldest first, the last one is checkout_sha
+//
+//go:embed events.json
+var defaultEvents []byte
+
+type Config struct {
+	// Events adds or replaces the mapping rules of events.json by event type.
+	Events mapping.Rules `json:"events"`
+}
+
+type Parser struct {
+	events mapping.Rules
+}
+
+func New(config Config) (*Parser, error) {
+	events, err := mapping.Load(defaultEvents, config.Events)
+	if err != nil {
+		return nil, fmt.Errorf("invalid events config: %w", err)
+	}
+	return &Parser{events: events}, nil
+}
+
+func (p *Parser) Source() string {
+	return "gitlab"
+}
+
+func (p *Parser) Parse(ctx context.Context, headers map[string][]string, body []byte) (*shared.EventRecord, error) {
+	logger := shared.LoggerFromContext(ctx)
+
+	metadata, err := shared.DecodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling data: %w", err)
+	}
+
+	eventType, ok := shared.LookupMap[string](metadata, "object_kind")
+	if !ok {
+		return nil, fmt.Errorf("could not find object_kind")
+	}
+	rule, ok := p.events.Lookup(eventType)
+	if !ok {
+		logger.Warn(fmt.Sprintf("event type %s is not supported", eventType))
+		parser.RecordSkip(ctx, eventType, parser.SkipUnsupported)
+		return nil, nil
+	}
+	if reason := rule.FilterReason(metadata); reason != "" {
+		logger.Info(fmt.Sprintf("%s event skipped: %s", eventType, reason))
+		parser.RecordSkip(ctx, eventType, parser.SkipFiltered)
+		return nil, nil
+	}
+
+	source := "gitlab"
+	if _, ok := headers["Mock"]; ok {
+		source = "gitlab_mock"
+	}
+
+	id, iErr := rule.EventID(metadata)
+	maybeTimeCreated, tErr := rule.TimeCreated(metadata)
+	if err := errors.Join(iErr, tErr); err != nil {
+		return nil, err
+	}
+	timeCreated, err := shared.ParseTimestamp(maybeTimeCreated)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse time_created: %w", err)
+	}
+
+	return &shared.EventRecord{
+		EventType:   eventType,
+		Id:          id,
+		Metadata:    string(body),
+		TimeCreated: timeCreated,
+		Signature:   parser.UniqueID(body),
+		Source:      source,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package gitlab
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func TestParse(t *testing.T) {
+	ctx := shared.WithLogger(context.Background())
+	p, err := New(Config{})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	t.Run("push", func(t *testing.T) {
+		body := []byte(`{"object_kind":"push","checkout_sha":"abc","commits":[{"id":"def","timestamp":"2022-01-04T00:00:00Z"},{"id":"abc","timestamp":"2022-01-05 04:36:28 -0800"}]}`)
+		e, err := p.Parse(ctx, map[string][]string{"X-Gitlab-Event": {"Push Hook"}}, body)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "abc" || e.Source != "gitlab" {
+			t.Errorf("record: %+v", e)
+		}
+		if want := time.Date(2022, 1, 5, 12, 36, 28, 0, time.UTC); !e.TimeCreated.Equal(want) {
+			t.Errorf("time_created: %v, want %v", e.TimeCreated, want)
+		}
+	})
+
+	t.Run("merge request with numeric id", func(t *testing.T) {
+		body := []byte(`{"object_kind":"merge_request","object_attributes":{"id":99,"updated_at":"2022-01-05T04:36:28Z"}}`)
+		e, err := p.Parse(ctx, map[string][]string{"Mock": {"true"}}, body)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "99" || e.Source != "gitlab_mock" {
+			t.Errorf("record: %+v", e)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		e, err := p.Parse(ctx, nil, []byte(`{"object_kind":"wiki_page"}`))
+		if err != nil || e != nil {
+			t.Errorf("expected skip, got %+v, %v", e, err)
+		}
+	})
+}
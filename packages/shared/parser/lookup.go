@@ -0,0 +1,21 @@
+package parser
+
+import (
+	"net/http"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+// LookupFirst returns the first non-empty string found at any of paths.
+func LookupFirst(m map[string]interface{}, paths ...[]string) (string, bool) {
+	for _, keys := range paths {
+		if v, ok := shared.LookupMap[string](m, keys...); ok && v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func Header(headers map[string][]string, key string) string {
+	return http.Header(headers).Get(key)
+}
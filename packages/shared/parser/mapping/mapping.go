@@ -0,0 +1,274 @@
+// Package mapping evaluates declarative rules that map a webhook payload to an event id and timestamp.
+//
+// A rule set is keyed by event type:
+//
+//	{
+//	  "merge_group": {
+//	    "id": ["merge_group.head_sha"],
+//	    "timestamp": ["merge_group.head_commit.timestamp"],
+//	    "filters": [{"path": "action", "in": ["checks_requested"]}]
+//	  }
+//	}
+//
+// id and timestamp are lists of shared.Path expressions tried in order. An id entry may also
+// be a template such as "{repository.name}/{number}", in which every placeholder has to resolve.
+// The rule of event type "*" applies to the event types without a rule of their own.
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type Rule struct {
+	ID        []string `json:"id"`
+	Timestamp []string `json:"timestamp"`
+	Filters   []Filter `json:"filters,omitempty"`
+	// Disabled removes a rule inherited from the defaults.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// compiled is set by Rules.Validate.
+	compiled *compiledRule
+}
+
+// Filter keeps an event only when the value at Path is in In and not in NotIn.
+// A missing value counts as the empty string.
+type Filter struct {
+	Path  string   `json:"path"`
+	In    []string `json:"in,omitempty"`
+	NotIn []string `json:"not_in,omitempty"`
+}
+
+// compiledRule holds the parsed paths of a Rule, in the order of its fields.
+type compiledRule struct {
+	ids        []template
+	timestamps []shared.Path
+	filters    []shared.Path
+}
+
+// template is an id expression: literals[i] precedes paths[i], the last literal ends it.
+// A plain path is a template with empty literals.
+type template struct {
+	literals []string
+	paths    []shared.Path
+}
+
+type Rules map[string]Rule
+
+// anyEventType keys the rule used for event types without a rule of their own.
+const anyEventType = "*"
+
+// Load returns the rules of defaults, a JSON rule set, merged with override and validated.
+func Load(defaults []byte, override Rules) (Rules, error) {
+	var rules Rules
+	if err := json.Unmarshal(defaults, &rules); err != nil {
+		return nil, fmt.Errorf("invalid default rules: %w", err)
+	}
+	rules = rules.Merge(override)
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Lookup returns the rule of eventType, or the "*" rule.
+func (r Rules) Lookup(eventType string) (Rule, bool) {
+	if rule, ok := r[eventType]; ok {
+		return rule, true
+	}
+	rule, ok := r[anyEventType]
+	return rule, ok
+}
+
+// Merge returns r with the rules in override replacing those of the same event type.
+func (r Rules) Merge(override Rules) Rules {
+	merged := make(Rules, len(r)+len(override))
+	for eventType, rule := range r {
+		merged[eventType] = rule
+	}
+	for eventType, rule := range override {
+		if rule.Disabled {
+			delete(merged, eventType)
+			continue
+		}
+		merged[eventType] = rule
+	}
+	return merged
+}
+
+// Validate checks every rule and compiles its paths, so that evaluating the rules
+// does not parse them again.
+func (r Rules) Validate() error {
+	for eventType, rule := range r {
+		if rule.Disabled {
+			continue
+		}
+		if len(rule.ID) == 0 {
+			return fmt.Errorf("event %s: id is required", eventType)
+		}
+		if len(rule.Timestamp) == 0 {
+			return fmt.Errorf("event %s: timestamp is required", eventType)
+		}
+		c, err := rule.compile()
+		if err != nil {
+			return fmt.Errorf("event %s: %w", eventType, err)
+		}
+		rule.compiled = c
+		r[eventType] = rule
+	}
+	return nil
+}
+
+func (r Rule) compile() (*compiledRule, error) {
+	c := &compiledRule{}
+	for _, id := range r.ID {
+		t, err := parseTemplate(id)
+		if err != nil {
+			return nil, err
+		}
+		c.ids = append(c.ids, t)
+	}
+	for _, expr := range r.Timestamp {
+		p, err := shared.ParsePath(expr)
+		if err != nil {
+			return nil, err
+		}
+		c.timestamps = append(c.timestamps, p)
+	}
+	for _, f := range r.Filters {
+		if f.Path == "" {
+			return nil, fmt.Errorf("filter path is required")
+		}
+		p, err := shared.ParsePath(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		c.filters = append(c.filters, p)
+	}
+	return c, nil
+}
+
+// paths returns the compiled paths, compiling them now for rules that were not validated.
+func (r Rule) paths() (*compiledRule, error) {
+	if r.compiled != nil {
+		return r.compiled, nil
+	}
+	return r.compile()
+}
+
+// FilterReason returns a non-empty reason when a filter rejects metadata.
+func (r Rule) FilterReason(metadata map[string]interface{}) string {
+	c, err := r.paths()
+	if err != nil {
+		return err.Error()
+	}
+	for i, f := range r.Filters {
+		v, _ := lookup(metadata, c.filters[i])
+		if len(f.In) > 0 && !contains(f.In, v) {
+			return fmt.Sprintf("%s=%q is not in %v", f.Path, v, f.In)
+		}
+		if contains(f.NotIn, v) {
+			return fmt.Sprintf("%s=%q is excluded", f.Path, v)
+		}
+	}
+	return ""
+}
+
+// EventID returns the first id that resolves.
+func (r Rule) EventID(metadata map[string]interface{}) (string, error) {
+	c, err := r.paths()
+	if err != nil {
+		return "", err
+	}
+	for _, t := range c.ids {
+		if id, ok := t.evaluate(metadata); ok {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("could not find id in %s", strings.Join(r.ID, ", "))
+}
+
+// TimeCreated returns the first timestamp that resolves, unparsed.
+func (r Rule) TimeCreated(metadata map[string]interface{}) (string, error) {
+	c, err := r.paths()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range c.timestamps {
+		if v, ok := lookup(metadata, p); ok && v != "" {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("could not find time_created in %s", strings.Join(r.Timestamp, ", "))
+}
+
+func parseTemplate(expr string) (template, error) {
+	var t template
+	if strings.Count(expr, "{") != strings.Count(expr, "}") {
+		return t, fmt.Errorf("unbalanced braces in id %q", expr)
+	}
+	if !strings.Contains(expr, "{") {
+		p, err := shared.ParsePath(expr)
+		if err != nil {
+			return t, err
+		}
+		return template{literals: []string{"", ""}, paths: []shared.Path{p}}, nil
+	}
+
+	rest := expr
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			t.literals = append(t.literals, rest)
+			return t, nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return t, fmt.Errorf("unbalanced braces in id %q", expr)
+		}
+		p, err := shared.ParsePath(rest[start+1 : start+end])
+		if err != nil {
+			return t, err
+		}
+		t.literals = append(t.literals, rest[:start])
+		t.paths = append(t.paths, p)
+		rest = rest[start+end+1:]
+	}
+}
+
+// evaluate resolves t only when every path resolves to a non-empty value.
+func (t template) evaluate(metadata map[string]interface{}) (string, bool) {
+	var b strings.Builder
+	for i, p := range t.paths {
+		v, ok := lookup(metadata, p)
+		if !ok || v == "" {
+			return "", false
+		}
+		b.WriteString(t.literals[i])
+		b.WriteString(v)
+	}
+	b.WriteString(t.literals[len(t.paths)])
+	return b.String(), true
+}
+
+// lookup returns the string or number at path.
+func lookup(metadata map[string]interface{}, path shared.Path) (string, bool) {
+	v, err := path.Lookup(metadata)
+	if err != nil {
+		return "", false
+	}
+	s, err := shared.ToString(v)
+	return s, err == nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
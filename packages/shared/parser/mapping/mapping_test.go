@@ -0,0 +1,151 @@
+package mapping
+
+import (
+	"testing"
+)
+
+func TestRule(t *testing.T) {
+	metadata := map[string]interface{}{
+		"action": "completed",
+		"number": float64(42),
+		"repository": map[string]interface{}{
+			"name": "fourkeys-go",
+		},
+		"check_run": map[string]interface{}{
+			"id":         float64(1985130141),
+			"started_at": "2024-01-01T00:00:00Z",
+		},
+	}
+
+	t.Run("id template", func(t *testing.T) {
+		r := Rule{ID: []string{"{repository.name}/{number}"}}
+		id, err := r.EventID(metadata)
+		if err != nil || id != "fourkeys-go/42" {
+			t.Errorf("id: %s, err: %v", id, err)
+		}
+	})
+
+	t.Run("id fallback with numeric value", func(t *testing.T) {
+		r := Rule{ID: []string{"{repository.name}/{missing}", "check_run.id"}}
+		id, err := r.EventID(metadata)
+		if err != nil || id != "1985130141" {
+			t.Errorf("id: %s, err: %v", id, err)
+		}
+	})
+
+	t.Run("array index and coalesce", func(t *testing.T) {
+		m := map[string]interface{}{
+			"commits": []interface{}{map[string]interface{}{"id": "abc"}},
+		}
+		r := Rule{ID: []string{"head_commit.id | commits[0].id"}}
+		id, err := r.EventID(m)
+		if err != nil || id != "abc" {
+			t.Errorf("id: %s, err: %v", id, err)
+		}
+	})
+
+	t.Run("timestamp precedence", func(t *testing.T) {
+		r := Rule{Timestamp: []string{"check_run.completed_at", "check_run.started_at"}}
+		ts, err := r.TimeCreated(metadata)
+		if err != nil || ts != "2024-01-01T00:00:00Z" {
+			t.Errorf("timestamp: %s, err: %v", ts, err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		r := Rule{ID: []string{"review.id"}}
+		if _, err := r.EventID(metadata); err == nil || err.Error() != "could not find id in review.id" {
+			t.Errorf("err: %v", err)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		cases := []struct {
+			filter   Filter
+			filtered bool
+		}{
+			{Filter{Path: "action", In: []string{"completed"}}, false},
+			{Filter{Path: "action", In: []string{"created"}}, true},
+			{Filter{Path: "action", NotIn: []string{"completed"}}, true},
+			{Filter{Path: "missing", NotIn: []string{""}}, true},
+		}
+		for _, c := range cases {
+			r := Rule{Filters: []Filter{c.filter}}
+			if got := r.FilterReason(metadata) != ""; got != c.filtered {
+				t.Errorf("filter %+v: filtered %v, want %v", c.filter, got, c.filtered)
+			}
+		}
+	})
+}
+
+func TestRulesMerge(t *testing.T) {
+	defaults := Rules{
+		"push":   {ID: []string{"head_commit.id"}, Timestamp: []string{"head_commit.timestamp"}},
+		"status": {ID: []string{"id"}, Timestamp: []string{"updated_at"}},
+	}
+	merged := defaults.Merge(Rules{
+		"status":      {Disabled: true},
+		"merge_group": {ID: []string{"merge_group.head_sha"}, Timestamp: []string{"merge_group.head_commit.timestamp"}},
+	})
+
+	if _, ok := merged["status"]; ok {
+		t.Errorf("status should be disabled")
+	}
+	if _, ok := merged["merge_group"]; !ok {
+		t.Errorf("merge_group should be added")
+	}
+	if _, ok := merged["push"]; !ok {
+		t.Errorf("push should be inherited")
+	}
+	if err := merged.Validate(); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+	if err := (Rules{"x": {ID: []string{"{a"}, Timestamp: []string{"t"}}}).Validate(); err == nil {
+		t.Errorf("expected unbalanced braces error")
+	}
+	if err := (Rules{"x": {ID: []string{"a[x]"}, Timestamp: []string{"t"}}}).Validate(); err == nil {
+		t.Errorf("expected invalid path error")
+	}
+}
+
+func TestRulesValidateCompiles(t *testing.T) {
+	rules := Rules{"push": {ID: []string{"{repository.name}/{head_commit.id}"}, Timestamp: []string{"head_commit.timestamp"}}}
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	r := rules["push"]
+	if r.compiled == nil || len(r.compiled.ids) != 1 || len(r.compiled.ids[0].paths) != 2 {
+		t.Fatalf("compiled: %+v", r.compiled)
+	}
+
+	metadata := map[string]interface{}{
+		"repository":  map[string]interface{}{"name": "app"},
+		"head_commit": map[string]interface{}{"id": "abc", "timestamp": "2024-01-01T00:00:00Z"},
+	}
+	if id, err := r.EventID(metadata); err != nil || id != "app/abc" {
+		t.Errorf("id: %s, err: %v", id, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defaults := []byte(`{
+		"*": {"id": ["event.id"], "timestamp": ["event.occurred_at"]},
+		"push": {"id": ["head_commit.id"], "timestamp": ["head_commit.timestamp"]}
+	}`)
+	rules, err := Load(defaults, Rules{"push": {Disabled: true}})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if r, ok := rules.Lookup("push"); !ok || r.ID[0] != "event.id" {
+		t.Errorf("disabled push should fall back to *: %+v", r)
+	}
+	if r, ok := rules.Lookup("incident.triggered"); !ok || r.compiled == nil {
+		t.Errorf("* rule: %+v", r)
+	}
+	if _, err := Load([]byte(`{"push": {"id": ["a[x]"], "timestamp": ["t"]}}`), nil); err == nil {
+		t.Errorf("expected invalid path error")
+	}
+	if _, ok := (Rules{}).Lookup("push"); ok {
+		t.Errorf("expected no rule")
+	}
+}
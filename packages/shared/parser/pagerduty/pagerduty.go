@@ -0,0 +1,84 @@
+package pagerduty
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser/mapping"
+)
+
+// NOTE: the default rule set only has a "*" rule, every event type is recorded
+//
+//go:embed events.json
+var defaultEvents []byte
+
+type Config struct {
+	// Events adds or replaces the mapping rules of events.json by event type.
+	Events mapping.Rules `json:"events"`
+}
+
+type Parser struct {
+	events mapping.Rules
+}
+
+func New(config Config) (*Parser, error) {
+	events, err := mapping.Load(defaultEvents, config.Events)
+	if err != nil {
+		return nil, fmt.Errorf("invalid events config: %w", err)
+	}
+	return &Parser{events: events}, nil
+}
+
+func (p *Parser) Source() string {
+	return "pagerduty"
+}
+
+// Parse handles PagerDuty V3 webhooks. By default every event type is recorded;
+// incidents.sql treats all of them as incident related.
+func (p *Parser) Parse(ctx context.Context, headers map[string][]string, body []byte) (*shared.EventRecord, error) {
+	logger := shared.LoggerFromContext(ctx)
+
+	metadata, err := shared.DecodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling data: %w", err)
+	}
+
+	eventType, err := shared.LookupMapE[string](metadata, "event", "event_type")
+	if err != nil {
+		return nil, err
+	}
+	rule, ok := p.events.Lookup(eventType)
+	if !ok {
+		logger.Warn(fmt.Sprintf("event type %s is not supported", eventType))
+		parser.RecordSkip(ctx, eventType, parser.SkipUnsupported)
+		return nil, nil
+	}
+	if reason := rule.FilterReason(metadata); reason != "" {
+		logger.Info(fmt.Sprintf("%s event skipped: %s", eventType, reason))
+		parser.RecordSkip(ctx, eventType, parser.SkipFiltered)
+		return nil, nil
+	}
+
+	id, iErr := rule.EventID(metadata)
+	maybeTimeCreated, tErr := rule.TimeCreated(metadata)
+	if err := errors.Join(iErr, tErr); err != nil {
+		return nil, err
+	}
+	timeCreated, err := shared.ParseTimestamp(maybeTimeCreated)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse time_created: %w", err)
+	}
+
+	return &shared.EventRecord{
+		EventType:   eventType,
+		Id:          id,
+		Metadata:    string(body),
+		TimeCreated: timeCreated,
+		Signature:   parser.Header(headers, "X-Pagerduty-Signature"),
+		Source:      "pagerduty",
+	}, nil
+}
@@ -0,0 +1,39 @@
+package pagerduty
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func TestParse(t *testing.T) {
+	ctx := shared.WithLogger(context.Background())
+	p, err := New(Config{})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	t.Run("incident triggered", func(t *testing.T) {
+		body := []byte(`{"event":{"id":"01DEN3","event_type":"incident.triggered","occurred_at":"2022-01-05T04:36:28.000Z","data":{"id":"PGR0VU2"}}}`)
+		e, err := p.Parse(ctx, map[string][]string{"X-Pagerduty-Signature": {"v1=abc"}}, body)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "01DEN3" || e.EventType != "incident.triggered" || e.Source != "pagerduty" || e.Signature != "v1=abc" {
+			t.Errorf("record: %+v", e)
+		}
+		if want := time.Date(2022, 1, 5, 4, 36, 28, 0, time.UTC); !e.TimeCreated.Equal(want) {
+			t.Errorf("time_created: %v, want %v", e.TimeCreated, want)
+		}
+	})
+
+	// NOTE: every PagerDuty event type is recorded, malformed events are errors instead
+	t.Run("no event type", func(t *testing.T) {
+		e, err := p.Parse(ctx, nil, []byte(`{"event":{"id":"01DEN3"}}`))
+		if err == nil {
+			t.Errorf("expected error, got %+v", e)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+// Package parser turns webhook payloads delivered through Pub/Sub into EventRecords.
+package parser
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type Parser interface {
+	Source() string
+	// Parse returns a nil record without error for events that are intentionally skipped,
+	// and tells why through RecordSkip.
+	// MsgId is filled in by the caller.
+	Parse(ctx context.Context, headers map[string][]string, body []byte) (*shared.EventRecord, error)
+}
+
+type Registry struct {
+	parsers map[string]Parser
+	aliases map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		parsers: map[string]Parser{},
+		aliases: map[string]string{},
+	}
+}
+
+func (r *Registry) Register(p Parser) {
+	if _, ok := r.parsers[p.Source()]; ok {
+		panic("parser already registered for " + p.Source())
+	}
+	r.parsers[p.Source()] = p
+}
+
+// Alias makes name (e.g. a topic or subscription) resolve to source.
+func (r *Registry) Alias(name, source string) {
+	r.aliases[name] = source
+}
+
+func (r *Registry) Lookup(name string) (Parser, bool) {
+	if source, ok := r.aliases[name]; ok {
+		name = source
+	}
+	p, ok := r.parsers[name]
+	return p, ok
+}
+
+func (r *Registry) Sources() []string {
+	sources := make([]string, 0, len(r.parsers))
+	for s := range r.parsers {
+		sources = append(sources, s)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Resolve picks the parser by the subscription name
+// ("projects/p/subscriptions/github" or "github"), then by the webhook headers.
+func (r *Registry) Resolve(subscription string, headers map[string][]string) (Parser, error) {
+	if subscription != "" {
+		if p, ok := r.Lookup(path.Base(subscription)); ok {
+			return p, nil
+		}
+	}
+	source := DetectSource(headers)
+	if p, ok := r.Lookup(source); ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("no parser for subscription %q and source %q", subscription, source)
+}
+
+// UniqueID identifies payloads that carry no signature of their own.
+func UniqueID(body []byte) string {
+	sum := sha1.Sum(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type fakeParser string
+
+func (p fakeParser) Source() string {
+	return string(p)
+}
+
+func (p fakeParser) Parse(context.Context, map[string][]string, []byte) (*shared.EventRecord, error) {
+	return nil, nil
+}
+
+func TestRegistryResolve(t *testing.T) {
+	r := NewRegistry()
+	r.Register(fakeParser("github"))
+	r.Register(fakeParser("gitlab"))
+	r.Register(fakeParser("cloud_build"))
+	r.Alias("cloud-builds", "cloud_build")
+
+	cases := []struct {
+		name         string
+		subscription string
+		headers      map[string][]string
+		want         string
+	}{
+		{"full subscription name", "projects/p/subscriptions/github", nil, "github"},
+		{"short subscription name", "gitlab", nil, "gitlab"},
+		{"alias", "projects/p/subscriptions/cloud-builds", nil, "cloud_build"},
+		{"header fallback", "projects/p/subscriptions/unknown", map[string][]string{"X-Gitlab-Event": {"Push Hook"}}, "gitlab"},
+		{"user agent", "", map[string][]string{"User-Agent": {"GitHub-Hookshot/abc"}}, "github"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := r.Resolve(c.subscription, c.headers)
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if p.Source() != c.want {
+				t.Errorf("source: %s, want %s", p.Source(), c.want)
+			}
+		})
+	}
+
+	if _, err := r.Resolve("pagerduty", map[string][]string{"X-Pagerduty-Signature": {"v1=x"}}); err == nil {
+		t.Errorf("expected error for unregistered source")
+	}
+}
@@ -0,0 +1,32 @@
+package parser
+
+import "context"
+
+const (
+	// SkipUnsupported marks event types the parser does not store.
+	SkipUnsupported = "unsupported"
+	// SkipFiltered marks events dropped by filters in the parser config.
+	SkipFiltered = "filtered"
+)
+
+// Skip tells why Parse returned a nil record.
+type Skip struct {
+	EventType string
+	Reason    string
+}
+
+type skipKey struct{}
+
+// WithSkip returns a context in which parsers record skipped events into s.
+func WithSkip(ctx context.Context, s *Skip) context.Context {
+	return context.WithValue(ctx, skipKey{}, s)
+}
+
+// RecordSkip is called by parsers before returning a nil record.
+// It does nothing unless the caller asked for it with WithSkip.
+func RecordSkip(ctx context.Context, eventType, reason string) {
+	if s, ok := ctx.Value(skipKey{}).(*Skip); ok {
+		s.EventType = eventType
+		s.Reason = reason
+	}
+}
@@ -0,0 +1,71 @@
+package tekton
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+	"github.com/sisisin-sandbox/fourkeys-go/shared/parser"
+)
+
+const eventTypePrefix = "dev.tekton.event."
+
+type Parser struct{}
+
+func New() *Parser {
+	return &Parser{}
+}
+
+func (p *Parser) Source() string {
+	return "tekton"
+}
+
+// Parse handles Tekton CloudEvents delivered in binary mode (Ce-* headers).
+func (p *Parser) Parse(ctx context.Context, headers map[string][]string, body []byte) (*shared.EventRecord, error) {
+	logger := shared.LoggerFromContext(ctx)
+
+	eventType := parser.Header(headers, "Ce-Type")
+	if !strings.HasPrefix(eventType, eventTypePrefix) {
+		logger.Warn(fmt.Sprintf("event type %s is not supported", eventType))
+		parser.RecordSkip(ctx, eventType, parser.SkipUnsupported)
+		return nil, nil
+	}
+
+	metadata, err := shared.DecodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling data: %w", err)
+	}
+
+	id := parser.Header(headers, "Ce-Id")
+	if id == "" {
+		return nil, fmt.Errorf("header Ce-Id not found")
+	}
+
+	maybeTimeCreated := parser.Header(headers, "Ce-Time")
+	if maybeTimeCreated == "" {
+		var ok bool
+		maybeTimeCreated, ok = parser.LookupFirst(metadata,
+			[]string{"pipelineRun", "status", "completionTime"},
+			[]string{"pipelineRun", "status", "startTime"},
+			[]string{"taskRun", "status", "completionTime"},
+			[]string{"taskRun", "status", "startTime"},
+		)
+		if !ok {
+			return nil, fmt.Errorf("could not find time_created")
+		}
+	}
+	timeCreated, err := shared.ParseTimestamp(maybeTimeCreated)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse time_created: %w", err)
+	}
+
+	return &shared.EventRecord{
+		EventType:   eventType,
+		Id:          id,
+		Metadata:    string(body),
+		TimeCreated: timeCreated,
+		Signature:   id,
+		Source:      "tekton",
+	}, nil
+}
@@ -0,0 +1,38 @@
+package tekton
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func TestParse(t *testing.T) {
+	ctx := shared.WithLogger(context.Background())
+	p := New()
+
+	t.Run("pipelinerun without Ce-Time", func(t *testing.T) {
+		body := []byte(`{"pipelineRun":{"status":{"startTime":"2022-01-05T04:00:00Z","completionTime":"2022-01-05T04:36:28Z"}}}`)
+		e, err := p.Parse(ctx, map[string][]string{
+			"Ce-Type": {"dev.tekton.event.pipelinerun.successful.v1"},
+			"Ce-Id":   {"a1b2"},
+		}, body)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if e.Id != "a1b2" || e.EventType != "dev.tekton.event.pipelinerun.successful.v1" || e.Source != "tekton" {
+			t.Errorf("record: %+v", e)
+		}
+		if want := time.Date(2022, 1, 5, 4, 36, 28, 0, time.UTC); !e.TimeCreated.Equal(want) {
+			t.Errorf("time_created: %v, want %v", e.TimeCreated, want)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		e, err := p.Parse(ctx, map[string][]string{"Ce-Type": {"com.example.other"}}, []byte(`{}`))
+		if err != nil || e != nil {
+			t.Errorf("expected skip, got %+v, %v", e, err)
+		}
+	})
+}
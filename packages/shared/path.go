@@ -0,0 +1,256 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled lookup expression for decoded JSON.
+//
+//	repository.full_name     nested keys
+//	commits[0].id            array index, negative indices count from the end
+//	commits[*].id            wildcard over an array, returns []interface{}
+//	labels.*                 wildcard over map values (sorted by key)
+//	review.id | comment.id   coalesce, the first alternative that resolves wins
+//
+// JSON null counts as not found so that coalesce falls through it.
+type Path struct {
+	expr         string
+	alternatives [][]pathSegment
+}
+
+type segmentKind int
+
+const (
+	segmentKey segmentKind = iota
+	segmentIndex
+	segmentWildcard
+)
+
+type pathSegment struct {
+	kind  segmentKind
+	key   string
+	index int
+	// raw is the expression up to and including this segment, used in errors.
+	raw string
+}
+
+// PathError pinpoints the segment at which a lookup failed.
+type PathError struct {
+	Path    string
+	Segment string
+	Err     error
+}
+
+func (e *PathError) Error() string {
+	if e.Segment == "" {
+		return fmt.Sprintf("path %s: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("path %s: at %s: %s", e.Path, e.Segment, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	ErrPathNotFound = errors.New("not found")
+	ErrPathType     = errors.New("unexpected type")
+)
+
+func ParsePath(expr string) (Path, error) {
+	p := Path{expr: expr}
+	for _, alt := range strings.Split(expr, "|") {
+		alt = strings.TrimSpace(alt)
+		segments, err := parseSegments(alt)
+		if err != nil {
+			return Path{}, fmt.Errorf("invalid path %q: %w", expr, err)
+		}
+		p.alternatives = append(p.alternatives, segments)
+	}
+	return p, nil
+}
+
+func MustParsePath(expr string) Path {
+	p, err := ParsePath(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p Path) String() string {
+	return p.expr
+}
+
+func parseSegments(expr string) ([]pathSegment, error) {
+	if expr == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	var segments []pathSegment
+	i := 0
+	expectKey := true
+	for i < len(expr) {
+		switch c := expr[i]; {
+		case c == '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ at offset %d", i)
+			}
+			inner := expr[i+1 : i+end]
+			i += end + 1
+			if inner == "*" {
+				segments = append(segments, pathSegment{kind: segmentWildcard, raw: expr[:i]})
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				segments = append(segments, pathSegment{kind: segmentIndex, index: n, raw: expr[:i]})
+			}
+			expectKey = false
+		case c == '.':
+			if expectKey {
+				return nil, fmt.Errorf("empty key at offset %d", i)
+			}
+			i++
+			expectKey = true
+			if i == len(expr) {
+				return nil, fmt.Errorf("trailing .")
+			}
+		default:
+			if !expectKey {
+				return nil, fmt.Errorf("missing . before offset %d", i)
+			}
+			end := strings.IndexAny(expr[i:], ".[")
+			if end < 0 {
+				end = len(expr) - i
+			}
+			key := expr[i : i+end]
+			i += end
+			if key == "*" {
+				segments = append(segments, pathSegment{kind: segmentWildcard, raw: expr[:i]})
+			} else {
+				segments = append(segments, pathSegment{kind: segmentKey, key: key, raw: expr[:i]})
+			}
+			expectKey = false
+		}
+	}
+	return segments, nil
+}
+
+// Lookup evaluates the path against v.
+func (p Path) Lookup(v interface{}) (interface{}, error) {
+	var errs []error
+	for _, segments := range p.alternatives {
+		result, err := lookupSegments(v, segments)
+		if err == nil {
+			return result, nil
+		}
+		var pe *PathError
+		if errors.As(err, &pe) {
+			pe.Path = p.expr
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	return nil, errors.Join(errs...)
+}
+
+func lookupSegments(v interface{}, segments []pathSegment) (interface{}, error) {
+	for i, seg := range segments {
+		if v == nil {
+			return nil, &PathError{Segment: seg.raw, Err: ErrPathNotFound}
+		}
+		switch seg.kind {
+		case segmentKey:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, &PathError{Segment: seg.raw, Err: fmt.Errorf("%w: %T is not an object", ErrPathType, v)}
+			}
+			if v, ok = m[seg.key]; !ok || v == nil {
+				return nil, &PathError{Segment: seg.raw, Err: ErrPathNotFound}
+			}
+		case segmentIndex:
+			a, ok := v.([]interface{})
+			if !ok {
+				return nil, &PathError{Segment: seg.raw, Err: fmt.Errorf("%w: %T is not an array", ErrPathType, v)}
+			}
+			idx := seg.index
+			if idx < 0 {
+				idx += len(a)
+			}
+			if idx < 0 || idx >= len(a) {
+				return nil, &PathError{Segment: seg.raw, Err: fmt.Errorf("%w: index %d out of range (len %d)", ErrPathNotFound, seg.index, len(a))}
+			}
+			if v = a[idx]; v == nil {
+				return nil, &PathError{Segment: seg.raw, Err: ErrPathNotFound}
+			}
+		case segmentWildcard:
+			var elems []interface{}
+			switch c := v.(type) {
+			case []interface{}:
+				elems = c
+			case map[string]interface{}:
+				keys := make([]string, 0, len(c))
+				for k := range c {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					elems = append(elems, c[k])
+				}
+			default:
+				return nil, &PathError{Segment: seg.raw, Err: fmt.Errorf("%w: %T is not an array or object", ErrPathType, v)}
+			}
+			// NOTE: elements without a match are skipped, the lookup fails only when none match
+			results := make([]interface{}, 0, len(elems))
+			var lastErr error
+			for _, e := range elems {
+				r, err := lookupSegments(e, segments[i+1:])
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				results = append(results, r)
+			}
+			if len(results) == 0 {
+				if lastErr != nil {
+					return nil, lastErr
+				}
+				return nil, &PathError{Segment: seg.raw, Err: fmt.Errorf("%w: no elements", ErrPathNotFound)}
+			}
+			return results, nil
+		}
+	}
+	return v, nil
+}
+
+// LookupPath evaluates expr against m and casts the result to T.
+func LookupPath[T any](m map[string]interface{}, expr string) (T, error) {
+	var zero T
+	p, err := ParsePath(expr)
+	if err != nil {
+		return zero, err
+	}
+	return LookupPathOf[T](m, p)
+}
+
+func LookupPathOf[T any](m map[string]interface{}, p Path) (T, error) {
+	var zero T
+	v, err := p.Lookup(m)
+	if err != nil {
+		return zero, err
+	}
+	result, ok := v.(T)
+	if !ok {
+		return zero, &PathError{Path: p.expr, Err: fmt.Errorf("%w: %T is not %T", ErrPathType, v, zero)}
+	}
+	return result, nil
+}
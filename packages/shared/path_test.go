@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLookupPath(t *testing.T) {
+	var m map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"ref": "refs/heads/main",
+		"head_commit": null,
+		"commits": [
+			{"id": "a", "author": {"name": "x"}},
+			{"id": "b"},
+			{"id": "c", "author": {"name": "z"}}
+		],
+		"labels": {"b": "two", "a": "one"},
+		"comment": {"id": 7}
+	}`), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		expr string
+		want interface{}
+	}{
+		{"ref", "refs/heads/main"},
+		{"commits[0].id", "a"},
+		{"commits[-1].id", "c"},
+		{"commits[*].id", []interface{}{"a", "b", "c"}},
+		{"commits[*].author.name", []interface{}{"x", "z"}},
+		{"labels.*", []interface{}{"one", "two"}},
+		{"head_commit.id | commits[0].id", "a"},
+		{"review.id | comment.id", float64(7)},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			p, err := ParsePath(c.expr)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got, err := p.Lookup(m)
+			if err != nil {
+				t.Fatalf("lookup: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+
+	errCases := []struct {
+		expr string
+		want string
+		is   error
+	}{
+		{"commits[5].id", "path commits[5].id: at commits[5]: not found: index 5 out of range (len 3)", ErrPathNotFound},
+		{"ref.name", "path ref.name: at ref.name: unexpected type: string is not an object", ErrPathType},
+		{"head_commit.id", "path head_commit.id: at head_commit: not found", ErrPathNotFound},
+		{"commits[*].missing", "path commits[*].missing: at commits[*].missing: not found", ErrPathNotFound},
+	}
+	for _, c := range errCases {
+		t.Run(c.expr, func(t *testing.T) {
+			_, err := MustParsePath(c.expr).Lookup(m)
+			if err == nil || err.Error() != c.want {
+				t.Errorf("error: %v, want %s", err, c.want)
+			}
+			if !errors.Is(err, c.is) {
+				t.Errorf("error %v is not %v", err, c.is)
+			}
+		})
+	}
+
+	t.Run("typed", func(t *testing.T) {
+		id, err := LookupPath[string](m, "commits[1].id")
+		if err != nil || id != "b" {
+			t.Errorf("id: %s, err: %v", id, err)
+		}
+		if _, err := LookupPath[string](m, "comment.id"); !errors.Is(err, ErrPathType) {
+			t.Errorf("error: %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, expr := range []string{"", "a..b", "a[", "a[x]", "a.", "a[0]b"} {
+			if _, err := ParsePath(expr); err == nil {
+				t.Errorf("%q: expected error", expr)
+			}
+		}
+	})
+}
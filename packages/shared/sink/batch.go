@@ -0,0 +1,122 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+const (
+	DefaultBatchMaxSize = 500
+	DefaultBatchMaxWait = time.Second
+
+	flushTimeout = time.Minute
+)
+
+var ErrClosed = errors.New("sink is closed")
+
+type BatchConfig struct {
+	// MaxSize flushes the buffer once it holds this many records.
+	MaxSize int
+	// MaxWait flushes the buffer this long after the first record was buffered.
+	MaxWait time.Duration
+}
+
+// Batcher buffers records from concurrent callers and writes them to the
+// underlying Sink in batches. Insert returns once the batch holding the
+// records has been written, so callers can still ack only after a successful write.
+type Batcher struct {
+	sink Sink
+	cfg  BatchConfig
+
+	mu      sync.Mutex
+	buf     []*shared.EventRecord
+	waiters []chan error
+	timer   *time.Timer
+	closed  bool
+	flushes sync.WaitGroup
+}
+
+func NewBatcher(s Sink, cfg BatchConfig) *Batcher {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultBatchMaxSize
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = DefaultBatchMaxWait
+	}
+	return &Batcher{sink: s, cfg: cfg}
+}
+
+func (b *Batcher) Insert(ctx context.Context, records ...*shared.EventRecord) error {
+	done := make(chan error, 1)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	b.buf = append(b.buf, records...)
+	b.waiters = append(b.waiters, done)
+	if len(b.buf) >= b.cfg.MaxSize {
+		b.flushLocked()
+	} else if b.timer == nil {
+		b.timer = time.AfterFunc(b.cfg.MaxWait, b.Flush)
+	}
+	b.mu.Unlock()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// NOTE: the records may still be written; inserts are deduplicated by msg_id where the sink supports it
+		return ctx.Err()
+	}
+}
+
+// Flush writes the buffered records without waiting for MaxSize or MaxWait.
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+func (b *Batcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.buf) == 0 {
+		return
+	}
+
+	records, waiters := b.buf, b.waiters
+	b.buf, b.waiters = nil, nil
+
+	b.flushes.Add(1)
+	go func() {
+		defer b.flushes.Done()
+
+		// NOTE: a batch outlives the requests that filled it, so it gets its own context
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		err := b.sink.Insert(ctx, records...)
+		cancel()
+
+		for _, w := range waiters {
+			w <- err
+		}
+	}()
+}
+
+// Close flushes the buffered records, waits for in-flight batches and closes the underlying Sink.
+func (b *Batcher) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	b.flushLocked()
+	b.mu.Unlock()
+
+	b.flushes.Wait()
+	return b.sink.Close()
+}
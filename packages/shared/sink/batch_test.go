@@ -0,0 +1,106 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]*shared.EventRecord
+	err     error
+	closed  bool
+}
+
+func (s *recordingSink) Insert(_ context.Context, records ...*shared.EventRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, records)
+	return s.err
+}
+
+func (s *recordingSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestBatcher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("flush by size", func(t *testing.T) {
+		s := &recordingSink{}
+		b := NewBatcher(s, BatchConfig{MaxSize: 3, MaxWait: time.Hour})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := b.Insert(ctx, &shared.EventRecord{}); err != nil {
+					t.Errorf("error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(s.batches) != 1 || len(s.batches[0]) != 3 {
+			t.Errorf("batches: %v", s.batches)
+		}
+	})
+
+	t.Run("flush by time", func(t *testing.T) {
+		s := &recordingSink{}
+		b := NewBatcher(s, BatchConfig{MaxSize: 100, MaxWait: 10 * time.Millisecond})
+
+		if err := b.Insert(ctx, &shared.EventRecord{}); err != nil {
+			t.Errorf("error: %v", err)
+		}
+		if len(s.batches) != 1 {
+			t.Errorf("batches: %v", s.batches)
+		}
+	})
+
+	t.Run("error is returned to every caller", func(t *testing.T) {
+		s := &recordingSink{err: errors.New("quota exceeded")}
+		b := NewBatcher(s, BatchConfig{MaxSize: 1})
+
+		if err := b.Insert(ctx, &shared.EventRecord{}); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("close flushes and rejects inserts", func(t *testing.T) {
+		s := &recordingSink{}
+		b := NewBatcher(s, BatchConfig{MaxSize: 100, MaxWait: time.Hour})
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- b.Insert(ctx, &shared.EventRecord{}) }()
+		for {
+			b.mu.Lock()
+			n := len(b.buf)
+			b.mu.Unlock()
+			if n == 1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if err := b.Close(); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if err := <-errCh; err != nil {
+			t.Errorf("error: %v", err)
+		}
+		if len(s.batches) != 1 || !s.closed {
+			t.Errorf("batches: %v, closed: %v", s.batches, s.closed)
+		}
+		if err := b.Insert(ctx, &shared.EventRecord{}); !errors.Is(err, ErrClosed) {
+			t.Errorf("error: %v", err)
+		}
+	})
+}
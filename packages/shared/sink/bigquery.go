@@ -0,0 +1,77 @@
+package sink
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type BigQuery struct {
+	client *bigquery.Client
+	table  *bigquery.Table
+}
+
+func NewBigQuery(ctx context.Context, projectID, dataset, table string) (*BigQuery, error) {
+	if projectID == "" {
+		projectID = bigquery.DetectProjectID
+	}
+	client, err := bigquery.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BigQuery{
+		client: client,
+		table:  client.Dataset(dataset).Table(table),
+	}, nil
+}
+
+func (b *BigQuery) Insert(ctx context.Context, records ...*shared.EventRecord) error {
+	rows := make([]*bigQueryRow, 0, len(records))
+	for _, r := range records {
+		rows = append(rows, (*bigQueryRow)(r))
+	}
+	return b.table.Inserter().Put(ctx, rows)
+}
+
+func (b *BigQuery) Close() error {
+	return b.client.Close()
+}
+
+type bigQueryRow shared.EventRecord
+
+func (r *bigQueryRow) Save() (map[string]bigquery.Value, string, error) {
+	row := map[string]bigquery.Value{
+		"event_type":   r.EventType,
+		"id":           r.Id,
+		"metadata":     r.Metadata,
+		"time_created": r.TimeCreated,
+		"signature":    r.Signature,
+		"msg_id":       r.MsgId,
+		"source":       r.Source,
+		"repository":   nullString(r.Repository),
+		"organization": nullString(r.Organization),
+		"actor":        nullString(r.Actor),
+		"ref":          nullString(r.Ref),
+		"commit_sha":   nullString(r.CommitSha),
+		"environment":  nullString(r.Environment),
+	}
+	if r.IsDefaultBranch != nil {
+		row["is_default_branch"] = *r.IsDefaultBranch
+	}
+	// NOTE: one Pub/Sub message produces one record, so msg_id is used for best-effort dedup
+	insertID := r.MsgId
+	if insertID == "" {
+		insertID = bigquery.NoDedupeID
+	}
+	return row, insertID, nil
+}
+
+func nullString(s string) bigquery.Value {
+	if s == "" {
+		return nil
+	}
+	return s
+}
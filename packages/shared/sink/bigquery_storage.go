@@ -0,0 +1,280 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+const recentMsgIdsSize = 10000
+
+// BigQueryStorage writes through the Storage Write API using a committed stream.
+//
+// Every append carries the offset it expects, so a retry of an append the server
+// already committed fails with ALREADY_EXISTS and is not written twice, and
+// messages Pub/Sub redelivers to the same instance are skipped by msg_id.
+// NOTE: a message can still be stored twice when the stream is lost after the
+// server committed it, when it is redelivered to another instance, or after a
+// restart. Events are exactly-once as read: Reader, metrics.FromEvents and the
+// events view keep one row per msg_id.
+type BigQueryStorage struct {
+	client     *managedwriter.Client
+	tableID    string
+	descriptor protoreflect.MessageDescriptor
+	normalized *descriptorpb.DescriptorProto
+	newStream  func(ctx context.Context) (appendStream, error)
+
+	mu     sync.Mutex
+	stream appendStream
+	offset int64
+	recent *recentSet
+}
+
+// appendStream appends rows at an offset and waits for the result.
+type appendStream interface {
+	append(ctx context.Context, rows [][]byte, offset int64) error
+	Close() error
+}
+
+type managedStream struct {
+	*managedwriter.ManagedStream
+}
+
+func (s managedStream) append(ctx context.Context, rows [][]byte, offset int64) error {
+	res, err := s.AppendRows(ctx, rows, managedwriter.WithOffset(offset))
+	if err != nil {
+		return err
+	}
+	_, err = res.GetResult(ctx)
+	return err
+}
+
+func NewBigQueryStorage(ctx context.Context, projectID, dataset, table string) (*BigQueryStorage, error) {
+	if projectID == "" {
+		projectID = bigquery.DetectProjectID
+	}
+
+	// NOTE: the row descriptor is derived from the live table schema
+	bq, err := bigquery.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := bq.Dataset(dataset).Table(table).Metadata(ctx)
+	projectID = bq.Project()
+	bq.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema of %s.%s: %w", dataset, table, err)
+	}
+
+	descriptor, normalized, err := rowDescriptor(meta.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := managedwriter.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &BigQueryStorage{
+		client:     client,
+		tableID:    managedwriter.TableParentFromParts(projectID, dataset, table),
+		descriptor: descriptor,
+		normalized: normalized,
+		recent:     newRecentSet(recentMsgIdsSize),
+	}
+	b.newStream = b.newManagedStream
+	return b, nil
+}
+
+func (b *BigQueryStorage) newManagedStream(ctx context.Context) (appendStream, error) {
+	stream, err := b.client.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(b.tableID),
+		managedwriter.WithType(managedwriter.CommittedStream),
+		managedwriter.WithSchemaDescriptor(b.normalized),
+		managedwriter.EnableWriteRetries(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return managedStream{stream}, nil
+}
+
+func rowDescriptor(schema bigquery.Schema) (protoreflect.MessageDescriptor, *descriptorpb.DescriptorProto, error) {
+	storageSchema, err := adapt.BQSchemaToStorageTableSchema(schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := adapt.StorageSchemaToProto2Descriptor(storageSchema, "root")
+	if err != nil {
+		return nil, nil, err
+	}
+	descriptor, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, nil, errors.New("schema descriptor is not a message descriptor")
+	}
+	normalized, err := adapt.NormalizeDescriptor(descriptor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return descriptor, normalized, nil
+}
+
+func (b *BigQueryStorage) Insert(ctx context.Context, records ...*shared.EventRecord) error {
+	rows := make([][]byte, 0, len(records))
+	var msgIds []string
+	for _, r := range records {
+		if r.MsgId != "" && b.seen(r.MsgId) {
+			continue
+		}
+		row, err := b.encode(r)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+		msgIds = append(msgIds, r.MsgId)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	stream, offset, err := b.reserve(ctx, len(rows))
+	if err != nil {
+		return err
+	}
+	// NOTE: appends run without the lock, offsets are reserved so they stay ordered
+	err = stream.append(ctx, rows, offset)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		// NOTE: the committed offset is unknown now, continue on a new stream
+		if b.stream == stream {
+			b.stream.Close()
+			b.stream = nil
+		}
+		return err
+	}
+	for _, id := range msgIds {
+		if id != "" {
+			b.recent.add(id)
+		}
+	}
+	return nil
+}
+
+func (b *BigQueryStorage) seen(msgId string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.recent.contains(msgId)
+}
+
+// reserve returns the current stream, opening one if needed, and the offset of n rows on it.
+func (b *BigQueryStorage) reserve(ctx context.Context, n int) (appendStream, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stream == nil {
+		stream, err := b.newStream(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		b.stream = stream
+		b.offset = 0
+	}
+	offset := b.offset
+	b.offset += int64(n)
+	return b.stream, offset, nil
+}
+
+func (b *BigQueryStorage) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var errs []error
+	if b.stream != nil {
+		errs = append(errs, b.stream.Close())
+		b.stream = nil
+	}
+	if b.client != nil {
+		errs = append(errs, b.client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (b *BigQueryStorage) encode(r *shared.EventRecord) ([]byte, error) {
+	msg := dynamicpb.NewMessage(b.descriptor)
+	fields := b.descriptor.Fields()
+
+	set := func(name string, v protoreflect.Value) {
+		if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+			msg.Set(fd, v)
+		}
+	}
+	setString := func(name, v string) {
+		if v != "" {
+			set(name, protoreflect.ValueOfString(v))
+		}
+	}
+
+	setString("event_type", r.EventType)
+	setString("id", r.Id)
+	setString("metadata", r.Metadata)
+	// NOTE: TIMESTAMP columns are encoded as microseconds since epoch
+	set("time_created", protoreflect.ValueOfInt64(r.TimeCreated.UnixMicro()))
+	setString("signature", r.Signature)
+	setString("msg_id", r.MsgId)
+	setString("source", r.Source)
+	if r.IsDefaultBranch != nil {
+		set("is_default_branch", protoreflect.ValueOfBool(*r.IsDefaultBranch))
+	}
+	setString("repository", r.Repository)
+	setString("organization", r.Organization)
+	setString("actor", r.Actor)
+	setString("ref", r.Ref)
+	setString("commit_sha", r.CommitSha)
+	setString("environment", r.Environment)
+
+	return proto.Marshal(msg)
+}
+
+// recentSet remembers the last n msg_ids written by this instance.
+type recentSet struct {
+	ids  map[string]struct{}
+	ring []string
+	next int
+}
+
+func newRecentSet(n int) *recentSet {
+	return &recentSet{ids: make(map[string]struct{}, n), ring: make([]string, n)}
+}
+
+func (s *recentSet) contains(id string) bool {
+	_, ok := s.ids[id]
+	return ok
+}
+
+func (s *recentSet) add(id string) {
+	if s.contains(id) {
+		return
+	}
+	if old := s.ring[s.next]; old != "" {
+		delete(s.ids, old)
+	}
+	s.ring[s.next] = id
+	s.ids[id] = struct{}{}
+	s.next = (s.next + 1) % len(s.ring)
+}
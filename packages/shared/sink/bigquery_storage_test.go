@@ -0,0 +1,178 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func TestBigQueryStorageEncode(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "event_type", Type: bigquery.StringFieldType},
+		{Name: "id", Type: bigquery.StringFieldType},
+		{Name: "time_created", Type: bigquery.TimestampFieldType},
+		{Name: "msg_id", Type: bigquery.StringFieldType},
+		{Name: "is_default_branch", Type: bigquery.BooleanFieldType},
+		{Name: "repository", Type: bigquery.StringFieldType},
+	}
+	descriptor, _, err := rowDescriptor(schema)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	b := &BigQueryStorage{descriptor: descriptor}
+
+	isDefault := false
+	created := time.Date(2024, 3, 1, 12, 0, 0, 123000, time.UTC)
+	row, err := b.encode(&shared.EventRecord{
+		EventType:       "push",
+		Id:              "abc",
+		TimeCreated:     created,
+		MsgId:           "1",
+		Source:          "github",
+		IsDefaultBranch: &isDefault,
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	msg := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(row, msg); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	fields := descriptor.Fields()
+	if got := msg.Get(fields.ByName("time_created")).Int(); got != created.UnixMicro() {
+		t.Errorf("time_created: %v", got)
+	}
+	if !msg.Has(fields.ByName("is_default_branch")) {
+		t.Errorf("is_default_branch should be set")
+	}
+	if msg.Has(fields.ByName("repository")) {
+		t.Errorf("repository should be null")
+	}
+}
+
+func TestRecentSet(t *testing.T) {
+	s := newRecentSet(2)
+	s.add("a")
+	s.add("b")
+	s.add("c")
+	if s.contains("a") || !s.contains("b") || !s.contains("c") {
+		t.Errorf("ids: %v", s.ids)
+	}
+}
+
+type fakeStream struct {
+	id      int
+	offsets []int64
+	errs    []error
+	closed  bool
+}
+
+func (s *fakeStream) append(_ context.Context, rows [][]byte, offset int64) error {
+	s.offsets = append(s.offsets, offset)
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func (s *fakeStream) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestBigQueryStorageInsert(t *testing.T) {
+	descriptor, _, err := rowDescriptor(bigquery.Schema{
+		{Name: "id", Type: bigquery.StringFieldType},
+		{Name: "msg_id", Type: bigquery.StringFieldType},
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var streams []*fakeStream
+	var errs [][]error
+	b := &BigQueryStorage{descriptor: descriptor, recent: newRecentSet(10)}
+	b.newStream = func(context.Context) (appendStream, error) {
+		s := &fakeStream{id: len(streams)}
+		if len(errs) > 0 {
+			s.errs, errs = errs[0], errs[1:]
+		}
+		streams = append(streams, s)
+		return s, nil
+	}
+	ctx := context.Background()
+	insert := func(msgIds ...string) error {
+		var records []*shared.EventRecord
+		for _, id := range msgIds {
+			records = append(records, &shared.EventRecord{Id: id, MsgId: id})
+		}
+		return b.Insert(ctx, records...)
+	}
+
+	t.Run("offsets advance", func(t *testing.T) {
+		if err := insert("1", "2"); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if err := insert("3"); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if len(streams) != 1 || !reflect.DeepEqual(streams[0].offsets, []int64{0, 2}) {
+			t.Errorf("offsets: %v", streams[0].offsets)
+		}
+	})
+
+	t.Run("redelivered msg_id is skipped", func(t *testing.T) {
+		if err := insert("3"); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if len(streams[0].offsets) != 2 {
+			t.Errorf("offsets: %v", streams[0].offsets)
+		}
+	})
+
+	t.Run("already exists counts as written", func(t *testing.T) {
+		streams[0].errs = []error{status.Error(codes.AlreadyExists, "offset already committed")}
+		if err := insert("4"); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if err := insert("5"); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if len(streams) != 1 || !reflect.DeepEqual(streams[0].offsets, []int64{0, 2, 3, 4}) {
+			t.Errorf("offsets: %v", streams[0].offsets)
+		}
+		if !b.recent.contains("4") {
+			t.Errorf("4 should be remembered")
+		}
+	})
+
+	t.Run("other errors reset the stream", func(t *testing.T) {
+		streams[0].errs = []error{errors.New("deadline exceeded")}
+		if err := insert("6"); err == nil {
+			t.Fatalf("expected error")
+		}
+		if !streams[0].closed || b.recent.contains("6") {
+			t.Errorf("stream closed: %v, 6 remembered: %v", streams[0].closed, b.recent.contains("6"))
+		}
+
+		if err := insert("6"); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if len(streams) != 2 || !reflect.DeepEqual(streams[1].offsets, []int64{0}) {
+			t.Errorf("streams: %d, offsets: %v", len(streams), streams[len(streams)-1].offsets)
+		}
+	})
+}
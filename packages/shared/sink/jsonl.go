@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+// JSONL appends one JSON encoded EventRecord per line.
+type JSONL struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewJSONL(path string) (*JSONL, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONL{f: f}, nil
+}
+
+func (j *JSONL) Insert(_ context.Context, records ...*shared.EventRecord) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	enc := json.NewEncoder(j.f)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *JSONL) Close() error {
+	return j.f.Close()
+}
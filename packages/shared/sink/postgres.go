@@ -0,0 +1,61 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const postgresCreateTable = `CREATE TABLE IF NOT EXISTS %s (
+	event_type TEXT,
+	id TEXT,
+	metadata TEXT,
+	time_created TIMESTAMPTZ,
+	signature TEXT,
+	msg_id TEXT,
+	source TEXT,
+	is_default_branch BOOLEAN,
+	repository TEXT,
+	organization TEXT,
+	actor TEXT,
+	ref TEXT,
+	commit_sha TEXT,
+	environment TEXT
+)`
+
+type Postgres struct {
+	*sqlSink
+}
+
+func NewPostgres(ctx context.Context, dsn, table string) (*Postgres, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSQLSink(ctx, db, table, fmt.Sprintf(postgresCreateTable, table), postgresPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+	return &Postgres{s}, nil
+}
+
+// NewPostgresReader opens an existing table for reading, without creating it.
+func NewPostgresReader(ctx context.Context, dsn, table string) (*Postgres, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSQLReader(ctx, db, table, postgresPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+	return &Postgres{s}, nil
+}
+
+func postgresPlaceholder(i int) string {
+	return fmt.Sprintf("$%d", i)
+}
@@ -0,0 +1,203 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+// Reader reads stored events back, e.g. to compute metrics outside BigQuery.
+type Reader interface {
+	// Read returns the events created in [from, to), ordered by time_created.
+	// Events stored more than once are returned once, see shared.UniqueByMsgId.
+	Read(ctx context.Context, from, to time.Time) ([]*shared.EventRecord, error)
+	Close() error
+}
+
+// OpenReader opens the store described by cfg for reading.
+// Nothing is created: a missing file or table is an error.
+// bigquery_storage reads through the regular BigQuery API.
+func OpenReader(ctx context.Context, cfg Config) (Reader, error) {
+	if cfg.Dataset == "" {
+		cfg.Dataset = DefaultDataset
+	}
+	if cfg.Table == "" {
+		cfg.Table = DefaultTable
+	}
+
+	switch cfg.Type {
+	case "", TypeBigQuery, TypeBigQueryStorage:
+		return NewBigQuery(ctx, cfg.ProjectID, cfg.Dataset, cfg.Table)
+	case TypePostgres:
+		return NewPostgresReader(ctx, cfg.DSN, cfg.Table)
+	case TypeSQLite:
+		return NewSQLiteReader(ctx, cfg.DSN, cfg.Table)
+	case TypeJSONL:
+		if _, err := os.Stat(cfg.DSN); err != nil {
+			return nil, fmt.Errorf("error opening %s: %w", cfg.DSN, err)
+		}
+		return &JSONLReader{path: cfg.DSN}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+}
+
+func (b *BigQuery) Read(ctx context.Context, from, to time.Time) ([]*shared.EventRecord, error) {
+	q := b.client.Query(fmt.Sprintf(
+		"SELECT %s FROM `%s.%s.%s` WHERE time_created >= @from AND time_created < @to"+
+			" QUALIFY msg_id IS NULL OR ROW_NUMBER() OVER (PARTITION BY msg_id ORDER BY time_created) = 1"+
+			" ORDER BY time_created",
+		strings.Join(columns, ", "), b.table.ProjectID, b.table.DatasetID, b.table.TableID,
+	))
+	q.Parameters = []bigquery.QueryParameter{
+		{Name: "from", Value: from},
+		{Name: "to", Value: to},
+	}
+	it, err := q.Read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s: %w", b.table.FullyQualifiedName(), err)
+	}
+
+	var records []*shared.EventRecord
+	for {
+		var row bigQueryReadRow
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, row.record())
+	}
+	return records, nil
+}
+
+type bigQueryReadRow struct {
+	EventType       string              `bigquery:"event_type"`
+	Id              string              `bigquery:"id"`
+	Metadata        string              `bigquery:"metadata"`
+	TimeCreated     time.Time           `bigquery:"time_created"`
+	Signature       bigquery.NullString `bigquery:"signature"`
+	MsgId           bigquery.NullString `bigquery:"msg_id"`
+	Source          string              `bigquery:"source"`
+	IsDefaultBranch bigquery.NullBool   `bigquery:"is_default_branch"`
+	Repository      bigquery.NullString `bigquery:"repository"`
+	Organization    bigquery.NullString `bigquery:"organization"`
+	Actor           bigquery.NullString `bigquery:"actor"`
+	Ref             bigquery.NullString `bigquery:"ref"`
+	CommitSha       bigquery.NullString `bigquery:"commit_sha"`
+	Environment     bigquery.NullString `bigquery:"environment"`
+}
+
+func (r *bigQueryReadRow) record() *shared.EventRecord {
+	e := &shared.EventRecord{
+		EventType:    r.EventType,
+		Id:           r.Id,
+		Metadata:     r.Metadata,
+		TimeCreated:  r.TimeCreated,
+		Signature:    r.Signature.StringVal,
+		MsgId:        r.MsgId.StringVal,
+		Source:       r.Source,
+		Repository:   r.Repository.StringVal,
+		Organization: r.Organization.StringVal,
+		Actor:        r.Actor.StringVal,
+		Ref:          r.Ref.StringVal,
+		CommitSha:    r.CommitSha.StringVal,
+		Environment:  r.Environment.StringVal,
+	}
+	if r.IsDefaultBranch.Valid {
+		e.IsDefaultBranch = &r.IsDefaultBranch.Bool
+	}
+	return e
+}
+
+func (s *sqlSink) Read(ctx context.Context, from, to time.Time) ([]*shared.EventRecord, error) {
+	rows, err := s.db.QueryContext(ctx, s.selectRange, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*shared.EventRecord
+	for rows.Next() {
+		var (
+			r                                              shared.EventRecord
+			signature, msgID                               sql.NullString
+			isDefaultBranch                                sql.NullBool
+			repository, organization, actor, ref, sha, env sql.NullString
+		)
+		err := rows.Scan(&r.EventType, &r.Id, &r.Metadata, &r.TimeCreated, &signature, &msgID, &r.Source,
+			&isDefaultBranch, &repository, &organization, &actor, &ref, &sha, &env)
+		if err != nil {
+			return nil, err
+		}
+		r.Signature, r.MsgId = signature.String, msgID.String
+		if isDefaultBranch.Valid {
+			r.IsDefaultBranch = &isDefaultBranch.Bool
+		}
+		r.Repository, r.Organization, r.Actor = repository.String, organization.String, actor.String
+		r.Ref, r.CommitSha, r.Environment = ref.String, sha.String, env.String
+		r.TimeCreated = r.TimeCreated.UTC()
+		records = append(records, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return shared.UniqueByMsgId(records), nil
+}
+
+// JSONLReader reads the files written by JSONL.
+type JSONLReader struct {
+	path string
+}
+
+func (j *JSONLReader) Read(_ context.Context, from, to time.Time) ([]*shared.EventRecord, error) {
+	f, err := os.Open(j.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*shared.EventRecord
+	scanner := bufio.NewScanner(f)
+	// NOTE: metadata holds whole webhook payloads
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var r shared.EventRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", j.path, line, err)
+		}
+		if r.TimeCreated.Before(from) || !r.TimeCreated.Before(to) {
+			continue
+		}
+		records = append(records, &r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(a, b int) bool {
+		return records[a].TimeCreated.Before(records[b].TimeCreated)
+	})
+	return shared.UniqueByMsgId(records), nil
+}
+
+func (j *JSONLReader) Close() error {
+	return nil
+}
@@ -0,0 +1,85 @@
+package sink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReaders(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	for _, typ := range []string{TypeJSONL, TypeSQLite} {
+		t.Run(typ, func(t *testing.T) {
+			cfg := Config{Type: typ, DSN: filepath.Join(dir, "events."+typ)}
+			s, err := Open(ctx, cfg)
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			// NOTE: inserted out of order to check the ordering of Read,
+			// and twice to check that redelivered messages are read once
+			records := testRecords()
+			if err := s.Insert(ctx, records[1], records[0], records[0]); err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("error: %v", err)
+			}
+
+			r, err := OpenReader(ctx, cfg)
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			defer r.Close()
+
+			got, err := r.Read(ctx, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if !reflect.DeepEqual(got, records) {
+				t.Errorf("got %+v, want %+v", got, records)
+			}
+
+			got, err = r.Read(ctx, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if len(got) != 0 {
+				t.Errorf("to is exclusive: %+v", got)
+			}
+		})
+	}
+}
+
+func TestOpenReaderMissing(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	for _, typ := range []string{TypeJSONL, TypeSQLite} {
+		t.Run(typ, func(t *testing.T) {
+			path := filepath.Join(dir, "missing."+typ)
+			if _, err := OpenReader(ctx, Config{Type: typ, DSN: path}); err == nil {
+				t.Errorf("expected error")
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%s must not be created: %v", path, err)
+			}
+		})
+	}
+
+	t.Run("sqlite without table", func(t *testing.T) {
+		path := filepath.Join(dir, "other.sqlite")
+		s, err := Open(ctx, Config{Type: TypeSQLite, DSN: path, Table: "other"})
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		s.Close()
+		if _, err := OpenReader(ctx, Config{Type: TypeSQLite, DSN: path}); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
@@ -0,0 +1,68 @@
+// Package sink stores parsed events.
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+type Sink interface {
+	Insert(ctx context.Context, records ...*shared.EventRecord) error
+	Close() error
+}
+
+const (
+	TypeBigQuery = "bigquery"
+	// TypeBigQueryStorage writes through the Storage Write API with offset checked appends.
+	// Like every sink it may store a redelivered message twice, readers keep one row per msg_id.
+	TypeBigQueryStorage = "bigquery_storage"
+	TypePostgres        = "postgres"
+	TypeSQLite          = "sqlite"
+	TypeJSONL           = "jsonl"
+
+	DefaultDataset = "four_keys"
+	DefaultTable   = "events_raw"
+)
+
+type Config struct {
+	// Type is one of TypeBigQuery (default), TypeBigQueryStorage, TypePostgres, TypeSQLite and TypeJSONL.
+	Type string
+	// DSN is the connection string for postgres and the file path for sqlite and jsonl.
+	DSN string
+
+	// ProjectID and Dataset are only used by bigquery and bigquery_storage.
+	ProjectID string
+	Dataset   string
+	// Table defaults to DefaultTable.
+	Table string
+}
+
+func Open(ctx context.Context, cfg Config) (Sink, error) {
+	if cfg.Dataset == "" {
+		cfg.Dataset = DefaultDataset
+	}
+	if cfg.Table == "" {
+		cfg.Table = DefaultTable
+	}
+
+	switch cfg.Type {
+	case "", TypeBigQuery:
+		return NewBigQuery(ctx, cfg.ProjectID, cfg.Dataset, cfg.Table)
+	case TypeBigQueryStorage:
+		return NewBigQueryStorage(ctx, cfg.ProjectID, cfg.Dataset, cfg.Table)
+	case TypePostgres:
+		return NewPostgres(ctx, cfg.DSN, cfg.Table)
+	case TypeSQLite:
+		return NewSQLite(ctx, cfg.DSN, cfg.Table)
+	case TypeJSONL:
+		return NewJSONL(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+}
+
+func IsBigQuery(typ string) bool {
+	return typ == "" || typ == TypeBigQuery || typ == TypeBigQueryStorage
+}
@@ -0,0 +1,102 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+func testRecords() []*shared.EventRecord {
+	isDefault := true
+	return []*shared.EventRecord{
+		{
+			EventType:       "push",
+			Id:              "abc",
+			Metadata:        `{"ref":"refs/heads/main"}`,
+			TimeCreated:     time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			MsgId:           "1",
+			Source:          "github",
+			IsDefaultBranch: &isDefault,
+			Repository:      "org/app",
+		},
+		{
+			EventType:   "issues",
+			Id:          "app/1",
+			Metadata:    `{}`,
+			TimeCreated: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+			MsgId:       "2",
+			Source:      "github",
+		},
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	s, err := Open(ctx, Config{Type: TypeJSONL, DSN: path})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := s.Insert(ctx, testRecords()...); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+
+	var got []shared.EventRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r shared.EventRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		got = append(got, r)
+	}
+	if len(got) != 2 {
+		t.Fatalf("records: %v", got)
+	}
+	if got[0].IsDefaultBranch == nil || !*got[0].IsDefaultBranch || got[1].IsDefaultBranch != nil {
+		t.Errorf("is_default_branch: %v, %v", got[0].IsDefaultBranch, got[1].IsDefaultBranch)
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLite(ctx, filepath.Join(t.TempDir(), "events.db"), DefaultTable)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Insert(ctx, testRecords()...); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var count, nullRepositories int
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*), COUNT(*) - COUNT(repository) FROM events_raw").Scan(&count, &nullRepositories)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if count != 2 || nullRepositories != 1 {
+		t.Errorf("count: %d, null repositories: %d", count, nullRepositories)
+	}
+}
+
+func TestOpenUnknownType(t *testing.T) {
+	if _, err := Open(context.Background(), Config{Type: "mysql"}); err == nil {
+		t.Errorf("expected error")
+	}
+}
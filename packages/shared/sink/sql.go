@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sisisin-sandbox/fourkeys-go/shared"
+)
+
+var columns = []string{
+	"event_type",
+	"id",
+	"metadata",
+	"time_created",
+	"signature",
+	"msg_id",
+	"source",
+	"is_default_branch",
+	"repository",
+	"organization",
+	"actor",
+	"ref",
+	"commit_sha",
+	"environment",
+}
+
+// sqlSink writes to a database/sql table whose columns mirror events_raw.
+type sqlSink struct {
+	db          *sql.DB
+	insert      string
+	selectRange string
+}
+
+func newSQLSink(ctx context.Context, db *sql.DB, table, createTable string, placeholder func(i int) string) (*sqlSink, error) {
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		return nil, errors.Join(fmt.Errorf("error creating table %s: %w", table, err), db.Close())
+	}
+	return newSQLStatements(db, table, placeholder), nil
+}
+
+// newSQLReader opens table for reading. Unlike newSQLSink nothing is created,
+// a missing table is an error.
+func newSQLReader(ctx context.Context, db *sql.DB, table string, placeholder func(i int) string) (*sqlSink, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", strings.Join(columns, ", "), table))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error reading table %s: %w", table, err), db.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	return newSQLStatements(db, table, placeholder), nil
+}
+
+func newSQLStatements(db *sql.DB, table string, placeholder func(i int) string) *sqlSink {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = placeholder(i + 1)
+	}
+	return &sqlSink{
+		db: db,
+		insert: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			table, strings.Join(columns, ", "), strings.Join(placeholders, ", ")),
+		selectRange: fmt.Sprintf("SELECT %s FROM %s WHERE time_created >= %s AND time_created < %s ORDER BY time_created",
+			strings.Join(columns, ", "), table, placeholder(1), placeholder(2)),
+	}
+}
+
+func (s *sqlSink) Insert(ctx context.Context, records ...*shared.EventRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, s.insert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		if _, err := stmt.ExecContext(ctx, sqlValues(r)...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlSink) Close() error {
+	return s.db.Close()
+}
+
+func sqlValues(r *shared.EventRecord) []interface{} {
+	return []interface{}{
+		r.EventType,
+		r.Id,
+		r.Metadata,
+		r.TimeCreated.UTC(),
+		r.Signature,
+		r.MsgId,
+		r.Source,
+		sqlNullBool(r.IsDefaultBranch),
+		sqlNullString(r.Repository),
+		sqlNullString(r.Organization),
+		sqlNullString(r.Actor),
+		sqlNullString(r.Ref),
+		sqlNullString(r.CommitSha),
+		sqlNullString(r.Environment),
+	}
+}
+
+func sqlNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func sqlNullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
@@ -0,0 +1,68 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteCreateTable = `CREATE TABLE IF NOT EXISTS %s (
+	event_type TEXT,
+	id TEXT,
+	metadata TEXT,
+	time_created TIMESTAMP,
+	signature TEXT,
+	msg_id TEXT,
+	source TEXT,
+	is_default_branch BOOLEAN,
+	repository TEXT,
+	organization TEXT,
+	actor TEXT,
+	ref TEXT,
+	commit_sha TEXT,
+	environment TEXT
+)`
+
+type SQLite struct {
+	*sqlSink
+}
+
+func NewSQLite(ctx context.Context, path, table string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// NOTE: sqlite allows a single writer at a time
+	db.SetMaxOpenConns(1)
+
+	s, err := newSQLSink(ctx, db, table, fmt.Sprintf(sqliteCreateTable, table), sqlitePlaceholder)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLite{s}, nil
+}
+
+// NewSQLiteReader opens an existing database for reading.
+// NOTE: sqlite creates missing files on open, so a mistyped path would read as empty
+func NewSQLiteReader(ctx context.Context, path, table string) (*SQLite, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", path, err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSQLReader(ctx, db, table, sqlitePlaceholder)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLite{s}, nil
+}
+
+func sqlitePlaceholder(int) string {
+	return "?"
+}
@@ -0,0 +1,35 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectExtract(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "publish")
+	defer span.End()
+
+	attrs := map[string]string{"ce-id": "1"}
+	Inject(ctx, attrs)
+	if attrs["traceparent"] == "" {
+		t.Fatalf("traceparent not injected: %v", attrs)
+	}
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), attrs))
+	if got.TraceID() != span.SpanContext().TraceID() || !got.IsRemote() {
+		t.Errorf("extracted %v, want trace %s", got, span.SpanContext().TraceID())
+	}
+}
+
+func TestUnknownExporter(t *testing.T) {
+	if _, err := newExporter(context.Background(), "zipkin", ""); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,74 @@
+package shared
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts mirrors function_multiFormatParseTimestamp.sql, followed by the formats
+// BigQuery's CAST(input AS TIMESTAMP) fallback accepts. Layouts without a zone are read as UTC.
+var timestampLayouts = []string{
+	// 2022-01-05 04:36:28 -0800
+	"2006-01-02 15:04:05 -0700",
+	// 2022-01-12T09:47:26.948+01:00, 2022-01-12T09:47:26Z
+	time.RFC3339Nano,
+	// 2022-01-12T09:47:26.948-0100
+	"2006-01-02T15:04:05.999999999-0700",
+	// 2022-01-18 05:35:35.320020, 2022-01-18 05:35:35
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 MST",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ParseTimestamp parses the timestamp formats sent by the supported providers,
+// as well as Unix epoch seconds or milliseconds. The result is in UTC.
+func ParseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+	if isEpoch(s) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid epoch timestamp %q: %w", s, err)
+		}
+		return epochTime(f), nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported timestamp format %q", s)
+}
+
+func isEpoch(s string) bool {
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" {
+		return false
+	}
+	dot := false
+	for _, c := range digits {
+		switch {
+		case c == '.' && !dot:
+			dot = true
+		case c < '0' || c > '9':
+			return false
+		}
+	}
+	return true
+}
+
+// epochTime reads f as seconds, or as milliseconds when it is too large to be seconds.
+func epochTime(f float64) time.Time {
+	// NOTE: 1e11 seconds is in the year 5138
+	if math.Abs(f) >= 1e11 {
+		return time.UnixMicro(int64(math.Round(f * 1e3))).UTC()
+	}
+	return time.UnixMicro(int64(math.Round(f * 1e6))).UTC()
+}
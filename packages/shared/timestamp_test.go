@@ -0,0 +1,45 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	cases := []struct {
+		input string
+		want  time.Time
+	}{
+		{"2022-01-05 04:36:28 -0800", time.Date(2022, 1, 5, 12, 36, 28, 0, time.UTC)},
+		{"2022-01-05 04:36:28 +0800", time.Date(2022, 1, 4, 20, 36, 28, 0, time.UTC)},
+		{"2022-01-12T09:47:26.948+01:00", time.Date(2022, 1, 12, 8, 47, 26, 948000000, time.UTC)},
+		{"2022-01-12T09:47:26.948-0100", time.Date(2022, 1, 12, 10, 47, 26, 948000000, time.UTC)},
+		{"2022-01-18 05:35:35.320020", time.Date(2022, 1, 18, 5, 35, 35, 320020000, time.UTC)},
+		{"2022-01-18 05:35:35", time.Date(2022, 1, 18, 5, 35, 35, 0, time.UTC)},
+		{"2022-01-18T05:35:35Z", time.Date(2022, 1, 18, 5, 35, 35, 0, time.UTC)},
+		{"2022-01-18T05:35:35", time.Date(2022, 1, 18, 5, 35, 35, 0, time.UTC)},
+		{"2022-01-18 05:35:35+09:00", time.Date(2022, 1, 17, 20, 35, 35, 0, time.UTC)},
+		{"2022-01-18 05:35:35 UTC", time.Date(2022, 1, 18, 5, 35, 35, 0, time.UTC)},
+		{"2022-01-18", time.Date(2022, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"1641357388", time.Date(2022, 1, 5, 4, 36, 28, 0, time.UTC)},
+		{"1641357388.5", time.Date(2022, 1, 5, 4, 36, 28, 500000000, time.UTC)},
+		{"1641357388123", time.Date(2022, 1, 5, 4, 36, 28, 123000000, time.UTC)},
+	}
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			got, err := ParseTimestamp(c.input)
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if !got.Equal(c.want) || got.Location() != time.UTC {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "yesterday", "2022-13-01", "01/05/2022", "1.2.3"} {
+		if _, err := ParseTimestamp(input); err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+}